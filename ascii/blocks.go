@@ -0,0 +1,107 @@
+package ascii
+
+import (
+	"context"
+	"image"
+	"image/color"
+
+	"github.com/victorvbello/img-processing/pixelextract"
+)
+
+const (
+	// BRAILLE_BASE is the braille pattern without dots, the 8 dots of a 2x4
+	// cell are the bits added to it
+	BRAILLE_BASE = 0x2800
+	UPPER_HALF   = "▀"
+	LOWER_HALF   = "▄"
+)
+
+// brailleDots is the bit of the dot of column x and row y of a cell
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// Braille make a grid of cols columns where every cell pack 2x4 pixels in a
+// braille character, a dot is set for the light pixels (the dark ones when
+// invert is true) and the color of the cell is the average of its dots
+func Braille(ctx context.Context, img image.Image, cols int, invert bool) (*Grid, error) {
+	cols, rows, err := gridSize(img.Bounds(), cols)
+	if err != nil {
+		return nil, err
+	}
+	w, h := cols*2, rows*4
+	colors, err := resample(ctx, img, w, h)
+	if err != nil {
+		return nil, err
+	}
+	g := &Grid{Cols: cols, Rows: rows, Cells: make([]Cell, cols*rows)}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			char := rune(BRAILLE_BASE)
+			var dots []color.RGBA
+			for y := 0; y < 4; y++ {
+				for x := 0; x < 2; x++ {
+					c := colors[(row*4+y)*w+col*2+x]
+					if c.A != 0 && pixelextract.IsLight(c) != invert {
+						char |= brailleDots[y][x]
+						dots = append(dots, c)
+					}
+				}
+			}
+			g.Cells[row*cols+col] = Cell{Char: string(char), Color: straight(mean(dots))}
+		}
+	}
+	return g, nil
+}
+
+// HalfBlock make a grid of cols columns where every cell is 1x2 pixels, the
+// upper half block is drawn in the color of the top pixel on the color of
+// the bottom one, a transparent pixel leave its half empty
+func HalfBlock(ctx context.Context, img image.Image, cols int) (*Grid, error) {
+	cols, rows, err := gridSize(img.Bounds(), cols)
+	if err != nil {
+		return nil, err
+	}
+	colors, err := resample(ctx, img, cols, rows*2)
+	if err != nil {
+		return nil, err
+	}
+	g := &Grid{Cols: cols, Rows: rows, Cells: make([]Cell, cols*rows)}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			top, bottom := colors[row*2*cols+col], colors[(row*2+1)*cols+col]
+			var cell Cell
+			switch {
+			case top.A == 0 && bottom.A == 0:
+				cell = Cell{Char: " "}
+			case top.A == 0:
+				cell = Cell{Char: LOWER_HALF, Color: straight(bottom)}
+			case bottom.A == 0:
+				cell = Cell{Char: UPPER_HALF, Color: straight(top)}
+			default:
+				cell = Cell{Char: UPPER_HALF, Color: straight(top), Background: straight(bottom)}
+			}
+			g.Cells[row*cols+col] = cell
+		}
+	}
+	return g, nil
+}
+
+// mean return the average of colors, transparent when there is none
+func mean(colors []color.RGBA) color.RGBA {
+	if len(colors) == 0 {
+		return color.RGBA{}
+	}
+	var sr, sg, sb, sa int
+	for _, c := range colors {
+		sr += int(c.R)
+		sg += int(c.G)
+		sb += int(c.B)
+		sa += int(c.A)
+	}
+	n := len(colors)
+	return color.RGBA{uint8(sr / n), uint8(sg / n), uint8(sb / n), uint8(sa / n)}
+}
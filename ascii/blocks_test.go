@@ -0,0 +1,101 @@
+package ascii
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+)
+
+var (
+	dark  = color.RGBA{0, 0, 0, 255}
+	light = color.RGBA{255, 255, 255, 255}
+)
+
+// cellImage make a 2x4 image, the pixels marked with 1 in rows are light,
+// with 0 dark and with a space transparent
+func cellImage(rows [4]string) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 4))
+	for y, r := range rows {
+		for x := 0; x < 2; x++ {
+			switch r[x] {
+			case '1':
+				img.SetRGBA(x, y, light)
+			case '0':
+				img.SetRGBA(x, y, dark)
+			}
+		}
+	}
+	return img
+}
+
+func TestBraille(t *testing.T) {
+	tests := []struct {
+		name   string
+		rows   [4]string
+		invert bool
+		want   rune
+	}{
+		{"no dot", [4]string{"00", "00", "00", "00"}, false, 0x2800},
+		{"dot 1", [4]string{"10", "00", "00", "00"}, false, 0x2801},
+		{"dot 4", [4]string{"01", "00", "00", "00"}, false, 0x2808},
+		{"left column", [4]string{"10", "10", "10", "10"}, false, 0x2847},
+		{"right column", [4]string{"01", "01", "01", "01"}, false, 0x28b8},
+		{"bottom row", [4]string{"00", "00", "00", "11"}, false, 0x28c0},
+		{"all dots", [4]string{"11", "11", "11", "11"}, false, 0x28ff},
+		{"inverted", [4]string{"00", "00", "00", "11"}, true, 0x283f},
+		// a transparent pixel is never a dot
+		{"transparent", [4]string{"  ", "  ", "  ", "  "}, true, 0x2800},
+	}
+	for _, tt := range tests {
+		g, err := Braille(context.Background(), cellImage(tt.rows), 1, tt.invert)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if g.Cols != 1 || g.Rows != 1 {
+			t.Fatalf("%s: grid %dx%d, want 1x1", tt.name, g.Cols, g.Rows)
+		}
+		if got := g.At(0, 0).Char; got != string(tt.want) {
+			t.Errorf("%s: got %U, want %U", tt.name, []rune(got)[0], tt.want)
+		}
+	}
+
+	// the color is the average of the dots only
+	g, err := Braille(context.Background(), cellImage([4]string{"10", "00", "00", "00"}), 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := g.At(0, 0).Color; c != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("dot color %v", c)
+	}
+}
+
+func TestHalfBlock(t *testing.T) {
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	none := color.RGBA{}
+	tests := []struct {
+		name        string
+		top, bottom color.RGBA
+		want        Cell
+	}{
+		{"both", red, blue, Cell{Char: UPPER_HALF, Color: color.NRGBA{255, 0, 0, 255}, Background: color.NRGBA{0, 0, 255, 255}}},
+		{"top only", red, none, Cell{Char: UPPER_HALF, Color: color.NRGBA{255, 0, 0, 255}}},
+		{"bottom only", none, blue, Cell{Char: LOWER_HALF, Color: color.NRGBA{0, 0, 255, 255}}},
+		{"none", none, none, Cell{Char: " "}},
+	}
+	for _, tt := range tests {
+		img := image.NewRGBA(image.Rect(0, 0, 1, 2))
+		img.SetRGBA(0, 0, tt.top)
+		img.SetRGBA(0, 1, tt.bottom)
+		g, err := HalfBlock(context.Background(), img, 1)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if g.Cols != 1 || g.Rows != 1 {
+			t.Fatalf("%s: grid %dx%d, want 1x1", tt.name, g.Cols, g.Rows)
+		}
+		if got := g.At(0, 0); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package ascii
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/victorvbello/img-processing/pixelextract"
+)
+
+const (
+	// CELL_RATIO is the height of a character cell divided by its width
+	CELL_RATIO = 2.0
+	// STANDARD_RAMP go from the densest character to the lightest one
+	STANDARD_RAMP   = "@%#*+=-:. "
+	MAX_COLOR_VALUE = 255
+)
+
+// Mapper choose the character of a cell from its color
+type Mapper interface {
+	Char(c color.RGBA) string
+}
+
+// ByteMapper write "1" for the light colors and "0" for the dark ones
+type ByteMapper struct{}
+
+func (ByteMapper) Char(c color.RGBA) string {
+	if pixelextract.IsLight(c) {
+		return "1"
+	}
+	return "0"
+}
+
+// RampMapper pick the character by gray level, Chars go from the densest
+// (black) to the lightest (white)
+type RampMapper struct {
+	Chars []string
+}
+
+// NewRampMapper split ramp in characters, the densest first
+func NewRampMapper(ramp string) RampMapper {
+	chars := make([]string, 0, len(ramp))
+	for _, c := range ramp {
+		chars = append(chars, string(c))
+	}
+	return RampMapper{chars}
+}
+
+func (rm RampMapper) Char(c color.RGBA) string {
+	gray := pixelextract.ColorGrayScale(c)
+	charIndex := int(float32(gray*uint32(len(rm.Chars)-1)) / float32(MAX_COLOR_VALUE))
+	return rm.Chars[charIndex]
+}
+
+// Invert return the mapper with the ramp reversed, for light text on a dark
+// background
+func (rm RampMapper) Invert() RampMapper {
+	chars := make([]string, len(rm.Chars))
+	for i, c := range rm.Chars {
+		chars[len(chars)-1-i] = c
+	}
+	return RampMapper{chars}
+}
+
+// Cell is a character and the average color of the pixels it replace, a
+// Background with alpha 0 leave the background of the output
+type Cell struct {
+	Char       string
+	Color      color.NRGBA
+	Background color.NRGBA
+}
+
+// Grid is the text version of an image, Cells are in row major order
+type Grid struct {
+	Cols, Rows int
+	Cells      []Cell
+}
+
+func (g *Grid) At(col, row int) Cell {
+	return g.Cells[row*g.Cols+col]
+}
+
+// Lines return the characters of every row
+func (g *Grid) Lines() []string {
+	lines := make([]string, g.Rows)
+	var b strings.Builder
+	for row := 0; row < g.Rows; row++ {
+		b.Reset()
+		for _, c := range g.Cells[row*g.Cols : (row+1)*g.Cols] {
+			b.WriteString(c.Char)
+		}
+		lines[row] = b.String()
+	}
+	return lines
+}
+
+// FromPixels make a cell per pixel
+func FromPixels(ctx context.Context, px *pixelextract.Pixels, m Mapper) (*Grid, error) {
+	g := &Grid{Cols: px.Rect.Dx(), Rows: px.Rect.Dy(), Cells: make([]Cell, 0, px.Len())}
+	currentY := px.Rect.Min.Y - 1
+	var err error
+	px.Range(func(x, y int, c color.RGBA) bool {
+		if currentY != y {
+			if err = ctx.Err(); err != nil {
+				return false
+			}
+			currentY = y
+		}
+		g.Cells = append(g.Cells, Cell{Char: m.Char(c), Color: straight(c)})
+		return true
+	})
+	return g, err
+}
+
+// Sample make a grid of cols columns, every cell is the average of its
+// pixels and the rows keep the aspect ratio of the image using CELL_RATIO
+func Sample(ctx context.Context, img image.Image, cols int, m Mapper) (*Grid, error) {
+	cols, rows, err := gridSize(img.Bounds(), cols)
+	if err != nil {
+		return nil, err
+	}
+	colors, err := resample(ctx, img, cols, rows)
+	if err != nil {
+		return nil, err
+	}
+	g := &Grid{Cols: cols, Rows: rows, Cells: make([]Cell, cols*rows)}
+	for i, c := range colors {
+		g.Cells[i] = Cell{Char: m.Char(c), Color: straight(c)}
+	}
+	return g, nil
+}
+
+// gridSize return the rows of a grid of cols columns that keep the aspect
+// ratio of bounds, cols is at most the width of bounds
+func gridSize(bounds image.Rectangle, cols int) (int, int, error) {
+	if cols < 1 {
+		return 0, 0, fmt.Errorf("ascii: %d columns, at least 1 is required", cols)
+	}
+	if bounds.Empty() {
+		return 0, 0, fmt.Errorf("ascii: empty image")
+	}
+	if cols > bounds.Dx() {
+		cols = bounds.Dx()
+	}
+	cellWidth := float64(bounds.Dx()) / float64(cols)
+	rows := int(float64(bounds.Dy()) / (cellWidth * CELL_RATIO))
+	if rows < 1 {
+		rows = 1
+	}
+	return cols, rows, nil
+}
+
+// resample return the average colors of the image split in w x h blocks, in
+// row major order, a block is at least a pixel
+func resample(ctx context.Context, img image.Image, w, h int) ([]color.RGBA, error) {
+	bounds := img.Bounds()
+	blockWidth := float64(bounds.Dx()) / float64(w)
+	blockHeight := float64(bounds.Dy()) / float64(h)
+
+	px := pixelextract.Extract(img)
+	colors := make([]color.RGBA, w*h)
+	for y := 0; y < h; y++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		minY := bounds.Min.Y + int(float64(y)*blockHeight)
+		maxY := bounds.Min.Y + int(float64(y+1)*blockHeight)
+		if maxY <= minY {
+			maxY = minY + 1
+		}
+		for x := 0; x < w; x++ {
+			minX := bounds.Min.X + int(float64(x)*blockWidth)
+			maxX := bounds.Min.X + int(float64(x+1)*blockWidth)
+			if maxX <= minX {
+				maxX = minX + 1
+			}
+			colors[y*w+x] = average(px, image.Rect(minX, minY, maxX, maxY))
+		}
+	}
+	return colors, nil
+}
+
+// average return the mean premultiplied color of the pixels of r
+func average(px *pixelextract.Pixels, r image.Rectangle) color.RGBA {
+	var sr, sg, sb, sa, n uint64
+	px.RangeRect(r, func(x, y int, c color.RGBA) bool {
+		sr += uint64(c.R)
+		sg += uint64(c.G)
+		sb += uint64(c.B)
+		sa += uint64(c.A)
+		n++
+		return true
+	})
+	if n == 0 {
+		return color.RGBA{}
+	}
+	return color.RGBA{uint8(sr / n), uint8(sg / n), uint8(sb / n), uint8(sa / n)}
+}
+
+// straight undo the alpha premultiplication of c
+func straight(c color.RGBA) color.NRGBA {
+	return color.NRGBAModel.Convert(c).(color.NRGBA)
+}
@@ -0,0 +1,97 @@
+package ascii
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestGridSize(t *testing.T) {
+	tests := []struct {
+		bounds   image.Rectangle
+		cols     int
+		wantCols int
+		wantRows int
+		wantErr  bool
+	}{
+		{image.Rect(0, 0, 100, 50), 50, 50, 12, false},
+		// no more columns than pixels
+		{image.Rect(0, 0, 100, 50), 200, 100, 25, false},
+		{image.Rect(10, 10, 30, 50), 10, 10, 10, false},
+		// at least a row
+		{image.Rect(0, 0, 10, 1), 10, 10, 1, false},
+		{image.Rect(0, 0, 10, 10), 0, 0, 0, true},
+		{image.Rect(0, 0, 0, 10), 10, 0, 0, true},
+	}
+	for _, tt := range tests {
+		cols, rows, err := gridSize(tt.bounds, tt.cols)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v %d columns: error %v", tt.bounds, tt.cols, err)
+			continue
+		}
+		if cols != tt.wantCols || rows != tt.wantRows {
+			t.Errorf("%v %d columns: got %dx%d, want %dx%d", tt.bounds, tt.cols, cols, rows, tt.wantCols, tt.wantRows)
+		}
+	}
+}
+
+// quarters make a w x h image whose four quarters are tl, tr, bl and br
+func quarters(w, h int, tl, tr, bl, br color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := tl
+			switch {
+			case x >= w/2 && y >= h/2:
+				c = br
+			case y >= h/2:
+				c = bl
+			case x >= w/2:
+				c = tr
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestSample(t *testing.T) {
+	black, white := color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}
+	gray := color.RGBA{128, 128, 128, 255}
+	// 8x8 in 4 columns: cells of 2x4 pixels, 2 rows
+	img := quarters(8, 8, black, white, gray, black)
+	tests := []struct {
+		name  string
+		m     Mapper
+		lines []string
+	}{
+		{"ramp", NewRampMapper(STANDARD_RAMP), []string{"@@  ", "++@@"}},
+		{"inverted ramp", NewRampMapper(STANDARD_RAMP).Invert(), []string{"  @@", "==  "}},
+		{"byte", ByteMapper{}, []string{"0011", "0000"}},
+	}
+	for _, tt := range tests {
+		g, err := Sample(context.Background(), img, 4, tt.m)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if g.Cols != 4 || g.Rows != 2 {
+			t.Fatalf("%s: grid %dx%d, want 4x2", tt.name, g.Cols, g.Rows)
+		}
+		lines := g.Lines()
+		for i := range tt.lines {
+			if lines[i] != tt.lines[i] {
+				t.Errorf("%s: line %d %q, want %q", tt.name, i, lines[i], tt.lines[i])
+			}
+		}
+	}
+
+	// a cell over two colors is their average
+	g, err := Sample(context.Background(), quarters(4, 4, black, white, black, white), 1, ByteMapper{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := g.At(0, 0).Color; c != (color.NRGBA{127, 127, 127, 255}) {
+		t.Errorf("average color %v", c)
+	}
+}
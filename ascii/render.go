@@ -0,0 +1,116 @@
+package ascii
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"strings"
+)
+
+const (
+	RENDER_TEXT = "text"
+	RENDER_ANSI = "ansi"
+	RENDER_HTML = "html"
+)
+
+// Renders return the names accepted by Write
+func Renders() []string {
+	return []string{RENDER_TEXT, RENDER_ANSI, RENDER_HTML}
+}
+
+// CheckRender return an error when render is not one of Renders
+func CheckRender(render string) error {
+	for _, r := range Renders() {
+		if r == render {
+			return nil
+		}
+	}
+	return fmt.Errorf("ascii render %q not available, use %s", render, strings.Join(Renders(), ", "))
+}
+
+// Write render g to w as plain text, 24-bit ANSI colored text or an HTML page
+func Write(w io.Writer, g *Grid, render string) error {
+	switch render {
+	case RENDER_TEXT:
+		return WriteText(w, g)
+	case RENDER_ANSI:
+		return WriteANSI(w, g)
+	case RENDER_HTML:
+		return WriteHTML(w, g)
+	}
+	return CheckRender(render)
+}
+
+// WriteText write a line of characters per row
+func WriteText(w io.Writer, g *Grid) error {
+	bw := bufio.NewWriter(w)
+	for _, l := range g.Lines() {
+		bw.WriteString(l)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// WriteANSI write every character with its color and background as 24-bit
+// ANSI escapes, the colors are only written when they change
+func WriteANSI(w io.Writer, g *Grid) error {
+	bw := bufio.NewWriter(w)
+	for row := 0; row < g.Rows; row++ {
+		var last, lastBackground color.NRGBA
+		for col := 0; col < g.Cols; col++ {
+			c := g.At(col, row)
+			if col == 0 || c.Color != last {
+				fmt.Fprintf(bw, "\x1b[38;2;%d;%d;%dm", c.Color.R, c.Color.G, c.Color.B)
+				last = c.Color
+			}
+			if c.Background != lastBackground {
+				if c.Background.A == 0 {
+					bw.WriteString("\x1b[49m")
+				} else {
+					fmt.Fprintf(bw, "\x1b[48;2;%d;%d;%dm", c.Background.R, c.Background.G, c.Background.B)
+				}
+				lastBackground = c.Background
+			}
+			bw.WriteString(c.Char)
+		}
+		bw.WriteString("\x1b[0m\n")
+	}
+	return bw.Flush()
+}
+
+// WriteHTML write a page with the grid in a <pre>, consecutive characters
+// of the same colors share a <span>
+func WriteHTML(w io.Writer, g *Grid) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<style>
+body { background: #000; margin: 0; }
+pre { font: 10px/1 monospace; letter-spacing: 0; margin: 0; }
+</style>
+</head>
+<body>
+<pre>
+`)
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; {
+			c := g.At(col, row)
+			fmt.Fprintf(bw, `<span style="color:#%02x%02x%02x`, c.Color.R, c.Color.G, c.Color.B)
+			if c.Background.A != 0 {
+				fmt.Fprintf(bw, `;background:#%02x%02x%02x`, c.Background.R, c.Background.G, c.Background.B)
+			}
+			bw.WriteString(`">`)
+			for ; col < g.Cols && g.At(col, row).Color == c.Color && g.At(col, row).Background == c.Background; col++ {
+				bw.WriteString(html.EscapeString(g.At(col, row).Char))
+			}
+			bw.WriteString("</span>")
+		}
+		bw.WriteByte('\n')
+	}
+	bw.WriteString("</pre>\n</body>\n</html>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,69 @@
+package ascii
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func testGrid() *Grid {
+	red := color.NRGBA{255, 0, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+	green := color.NRGBA{0, 255, 0, 255}
+	return &Grid{Cols: 4, Rows: 2, Cells: []Cell{
+		{Char: "a", Color: red},
+		{Char: "b", Color: red},
+		{Char: "<", Color: blue},
+		{Char: "d", Color: blue, Background: green},
+		{Char: "e", Color: blue, Background: green},
+		{Char: "f", Color: blue, Background: green},
+		{Char: "g", Color: blue},
+		{Char: "h", Color: red},
+	}}
+}
+
+func TestWriteANSI(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteANSI(&buf, testGrid()); err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1b[38;2;255;0;0mab\x1b[38;2;0;0;255m<\x1b[48;2;0;255;0md\x1b[0m\n" +
+		// every row start with its color and without background
+		"\x1b[38;2;0;0;255m\x1b[48;2;0;255;0mef\x1b[49mg\x1b[38;2;255;0;0mh\x1b[0m\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, testGrid()); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	start, end := strings.Index(page, "<pre>\n"), strings.Index(page, "</pre>")
+	if start < 0 || end < start {
+		t.Fatalf("no <pre> in %q", page)
+	}
+	want := `<span style="color:#ff0000">ab</span><span style="color:#0000ff">&lt;</span>` +
+		`<span style="color:#0000ff;background:#00ff00">d</span>` + "\n" +
+		`<span style="color:#0000ff;background:#00ff00">ef</span><span style="color:#0000ff">g</span>` +
+		`<span style="color:#ff0000">h</span>` + "\n"
+	if got := page[start+len("<pre>\n") : end]; got != want {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testGrid(), RENDER_TEXT); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "ab<d\nefgh\n" {
+		t.Errorf("got %q", got)
+	}
+	if err := Write(&buf, testGrid(), "svg"); err == nil {
+		t.Error("render svg: no error")
+	}
+}
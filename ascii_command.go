@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"github.com/victorvbello/img-processing/ascii"
+	"github.com/victorvbello/img-processing/experiment"
+)
+
+const (
+	MODE_RAMP       = "ramp"
+	MODE_BRAILLE    = "braille"
+	MODE_HALF_BLOCK = "half-block"
+)
+
+func asciiCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "ascii",
+		Usage: "Write the image as text, to stdout unless --output is set",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "render",
+				Usage: "Text output: text, ansi (24-bit colored text), html (colored page)",
+				Value: ascii.RENDER_TEXT,
+			},
+			&cli.IntFlag{
+				Name:  "cols",
+				Usage: "Columns of the text, the rows keep the aspect ratio of the image",
+				Value: 80,
+			},
+			&cli.StringFlag{
+				Name:  "charset",
+				Usage: "Characters from the densest to the lightest",
+				Value: ascii.STANDARD_RAMP,
+			},
+			&cli.StringFlag{
+				Name:  "weights",
+				Usage: "Embedded weight table (" + strings.Join(experiment.WeightTables(), ", ") + ") or a file made by character-pixel-weight, replace charset",
+			},
+			&cli.BoolFlag{
+				Name:  "invert",
+				Usage: "Reverse the characters, for light text on a dark background, with braille set the dots of the dark pixels",
+			},
+			&cli.StringFlag{
+				Name:  "mode",
+				Usage: "Characters: ramp (charset or weights), braille (2x4 pixels a character), half-block (1x2 pixels a character)",
+				Value: MODE_RAMP,
+			},
+		},
+		Action: asciiAction,
+	}
+}
+
+func asciiAction(c *cli.Context) error {
+	_, inputFile, err := inputFlags(c)
+	if err != nil {
+		return err
+	}
+	render := c.String("render")
+	if err := ascii.CheckRender(render); err != nil {
+		return err
+	}
+	var m ascii.RampMapper
+	switch mode := c.String("mode"); mode {
+	case MODE_RAMP:
+		if m, err = asciiMapper(c); err != nil {
+			return err
+		}
+	case MODE_BRAILLE:
+	case MODE_HALF_BLOCK:
+		// the pixels are the colors, plain text only keep the block character
+		if render == ascii.RENDER_TEXT {
+			return fmt.Errorf("ascii mode %s need --render %s or %s", mode, ascii.RENDER_ANSI, ascii.RENDER_HTML)
+		}
+	default:
+		return fmt.Errorf("ascii mode %q not available, use %s, %s or %s", mode, MODE_RAMP, MODE_BRAILLE, MODE_HALF_BLOCK)
+	}
+	s := time.Now()
+	img, err := decodeInput(inputFile)
+	if err != nil {
+		return err
+	}
+	var g *ascii.Grid
+	ctx, cols := filterContext(c), c.Int("cols")
+	switch c.String("mode") {
+	case MODE_BRAILLE:
+		g, err = ascii.Braille(ctx, img, cols, c.Bool("invert"))
+	case MODE_HALF_BLOCK:
+		g, err = ascii.HalfBlock(ctx, img, cols)
+	default:
+		g, err = ascii.Sample(ctx, img, cols, m)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("ascii %dx%d characters => %v", g.Cols, g.Rows, time.Since(s))
+	return writeASCII(c.String("output"), g, render)
+}
+
+// asciiMapper return the ramp of --weights or --charset
+func asciiMapper(c *cli.Context) (ascii.RampMapper, error) {
+	var m ascii.RampMapper
+	if weights := c.String("weights"); weights != "" {
+		charInfo, err := experiment.LoadWeights(weights)
+		if err != nil {
+			return m, fmt.Errorf("open-weight-file %w", err)
+		}
+		if m, err = charInfo.Mapper(); err != nil {
+			return m, fmt.Errorf("%s: %w", weights, err)
+		}
+	} else {
+		if c.String("charset") == "" {
+			return m, errors.New("ascii: charset is empty")
+		}
+		m = ascii.NewRampMapper(c.String("charset"))
+	}
+	if c.Bool("invert") {
+		m = m.Invert()
+	}
+	return m, nil
+}
+
+// writeASCII write g to path, stdout when path is empty or -, a file that
+// could not be written completely is removed
+func writeASCII(path string, g *ascii.Grid, render string) error {
+	if path == "" || path == "-" {
+		return ascii.Write(os.Stdout, g, render)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = ascii.Write(f, g, render)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	log.Println("ascii written to", path)
+	return nil
+}
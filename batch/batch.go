@@ -0,0 +1,169 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/victorvbello/img-processing/progress"
+)
+
+// Job is a single file of a batch, Rel is the path of Input relative to the
+// walked directory
+type Job struct {
+	Input  string
+	Output string
+	Rel    string
+}
+
+type Result struct {
+	Job
+	Err      error
+	Duration time.Duration
+}
+
+type ProcessFunc func(ctx context.Context, job Job) error
+
+// Summary hold the results of a batch in the order of its jobs
+type Summary struct {
+	Results  []Result
+	Duration time.Duration
+}
+
+func (s Summary) Failed() []Result {
+	var failed []Result
+	for _, r := range s.Results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+func (s Summary) Succeeded() int {
+	return len(s.Results) - len(s.Failed())
+}
+
+func match(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, rel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, filepath.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Walk list the files of dir matching any include pattern and no exclude
+// pattern, patterns are matched against the relative path and the base name.
+// Every job output mirror its relative path inside outDir
+func Walk(dir string, outDir string, include []string, exclude []string) ([]Job, error) {
+	var jobs []Job
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if len(include) > 0 && !match(include, rel) {
+			return nil
+		}
+		if match(exclude, rel) {
+			return nil
+		}
+		jobs = append(jobs, Job{Input: path, Output: filepath.Join(outDir, rel), Rel: rel})
+		return nil
+	})
+	return jobs, err
+}
+
+func runJob(ctx context.Context, job Job, process ProcessFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return process(ctx, job)
+}
+
+// jobObserver forward the logs and warnings of the filters of a job with its
+// path as stage, the progress of a batch is the number of finished jobs
+type jobObserver struct {
+	progress.Observer
+	rel string
+}
+
+func (jo jobObserver) Event(e progress.Event) {
+	if e.Kind != progress.LOG && e.Kind != progress.WARNING {
+		return
+	}
+	e.Stage = jo.rel
+	e.Percent, e.Step, e.Steps = 0, 0, 0
+	jo.Observer.Event(e)
+}
+
+// Run process the jobs on a pool of workers, a failed job never stop the
+// others. o receive a DONE or FAILED event per finished job, it can be nil
+func Run(ctx context.Context, jobs []Job, workers int, process ProcessFunc, o progress.Observer) Summary {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var finished int
+	s := time.Now()
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]Result, len(jobs))
+	jobsIndex := make(chan int)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobsIndex {
+				sj := time.Now()
+				jobCtx := progress.WithObserver(ctx, nil)
+				if o != nil {
+					jobCtx = progress.WithObserver(ctx, jobObserver{o, jobs[i].Rel})
+				}
+				err := runJob(jobCtx, jobs[i], process)
+				results[i] = Result{jobs[i], err, time.Since(sj)}
+
+				mu.Lock()
+				finished++
+				e := progress.Event{
+					Kind:     progress.DONE,
+					Stage:    jobs[i].Rel,
+					Step:     finished,
+					Steps:    len(jobs),
+					Percent:  progress.Percent(finished, len(jobs)),
+					Duration: results[i].Duration,
+				}
+				mu.Unlock()
+				if err != nil {
+					e.Kind, e.Message = progress.FAILED, err.Error()
+				}
+				progress.Notify(o, e)
+			}
+		}()
+	}
+
+	for i := range jobs {
+		jobsIndex <- i
+	}
+	close(jobsIndex)
+	wg.Wait()
+	return Summary{results, time.Since(s)}
+}
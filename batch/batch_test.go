@@ -0,0 +1,157 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/victorvbello/img-processing/progress"
+)
+
+func TestWalk(t *testing.T) {
+	dir := t.TempDir()
+	for _, rel := range []string{"a.png", "b.jpg", "c_raw.png", "sub/d.png", "sub/deep/e.gif"} {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{"all", nil, nil, []string{"a.png", "b.jpg", "c_raw.png", "sub/d.png", "sub/deep/e.gif"}},
+		{"include base name", []string{"*.png"}, nil, []string{"a.png", "c_raw.png", "sub/d.png"}},
+		{"include relative path", []string{"sub/*"}, nil, []string{"sub/d.png"}},
+		{"exclude", nil, []string{"*_raw.*", "*.gif"}, []string{"a.png", "b.jpg", "sub/d.png"}},
+		{"include and exclude", []string{"*.png"}, []string{"*_raw.*"}, []string{"a.png", "sub/d.png"}},
+		{"no match", []string{"*.bmp"}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, err := Walk(dir, "out", tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, j := range jobs {
+				rel := filepath.ToSlash(j.Rel)
+				got = append(got, rel)
+				if j.Input != filepath.Join(dir, j.Rel) || j.Output != filepath.Join("out", j.Rel) {
+					t.Errorf("%s: input %s output %s", rel, j.Input, j.Output)
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := Walk(filepath.Join(dir, "missing"), "out", nil, nil); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestRun(t *testing.T) {
+	jobs := make([]Job, 20)
+	for i := range jobs {
+		jobs[i] = Job{Rel: string(rune('a' + i))}
+	}
+	errFail := errors.New("fail")
+	var running, peak int32
+	process := func(ctx context.Context, job Job) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		switch job.Rel {
+		case "c":
+			return errFail
+		case "f":
+			panic("boom")
+		}
+		return nil
+	}
+	var mu sync.Mutex
+	kinds := map[progress.Kind]int{}
+	o := progress.ObserverFunc(func(e progress.Event) {
+		mu.Lock()
+		kinds[e.Kind]++
+		mu.Unlock()
+	})
+
+	s := Run(context.Background(), jobs, 3, process, o)
+	if peak > 3 {
+		t.Errorf("%d jobs at the same time, want at most 3", peak)
+	}
+	if len(s.Results) != len(jobs) {
+		t.Fatalf("%d results, want %d", len(s.Results), len(jobs))
+	}
+	for i, r := range s.Results {
+		if r.Rel != jobs[i].Rel {
+			t.Errorf("result %d is job %s, want %s", i, r.Rel, jobs[i].Rel)
+		}
+	}
+	if s.Results[2].Err != errFail {
+		t.Errorf("job c err = %v, want %v", s.Results[2].Err, errFail)
+	}
+	if err := s.Results[5].Err; err == nil || err.Error() != "panic: boom" {
+		t.Errorf("job f err = %v, want the panic", err)
+	}
+	if len(s.Failed()) != 2 || s.Succeeded() != 18 {
+		t.Errorf("%d failed and %d succeeded, want 2 and 18", len(s.Failed()), s.Succeeded())
+	}
+	if kinds[progress.DONE] != 18 || kinds[progress.FAILED] != 2 {
+		t.Errorf("events %v, want 18 done and 2 failed", kinds)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var called int32
+	s := Run(ctx, []Job{{Rel: "a"}, {Rel: "b"}}, 0, func(ctx context.Context, job Job) error {
+		atomic.AddInt32(&called, 1)
+		return nil
+	}, nil)
+	if called != 0 {
+		t.Errorf("process called %d times after the cancellation", called)
+	}
+	for _, r := range s.Results {
+		if r.Err != context.Canceled {
+			t.Errorf("job %s err = %v, want %v", r.Rel, r.Err, context.Canceled)
+		}
+	}
+}
+
+func TestJobObserver(t *testing.T) {
+	var got []progress.Event
+	o := jobObserver{progress.ObserverFunc(func(e progress.Event) { got = append(got, e) }), "sub/a.png"}
+	o.Event(progress.Event{Kind: progress.PROGRESS, Percent: 50})
+	o.Event(progress.Event{Kind: progress.WARNING, Message: "slow", Percent: 50, Step: 1, Steps: 2})
+	if len(got) != 1 {
+		t.Fatalf("%d events forwarded, want only the warning", len(got))
+	}
+	want := progress.Event{Kind: progress.WARNING, Message: "slow", Stage: "sub/a.png"}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("got %+v, want %+v", got[0], want)
+	}
+}
@@ -0,0 +1,71 @@
+package dither
+
+import (
+	"context"
+	"image"
+
+	"github.com/victorvbello/img-processing/progress"
+)
+
+// kernel spread the quantization error of a pixel to the pixels at dx, dy,
+// every weight is divided by the divisor
+type kernel struct {
+	divisor float32
+	weights []weight
+}
+
+type weight struct {
+	dx, dy int
+	w      float32
+}
+
+var floydSteinberg = kernel{16, []weight{
+	{1, 0, 7},
+	{-1, 1, 3}, {0, 1, 5}, {1, 1, 1},
+}}
+
+// atkinson spread 6/8 of the error, the lost part keep the contrast
+var atkinson = kernel{8, []weight{
+	{1, 0, 1}, {2, 0, 1},
+	{-1, 1, 1}, {0, 1, 1}, {1, 1, 1},
+	{0, 2, 1},
+}}
+
+var jarvisJudiceNinke = kernel{48, []weight{
+	{1, 0, 7}, {2, 0, 5},
+	{-2, 1, 3}, {-1, 1, 5}, {0, 1, 7}, {1, 1, 5}, {2, 1, 3},
+	{-2, 2, 1}, {-1, 2, 3}, {0, 2, 5}, {1, 2, 3}, {2, 2, 1},
+}}
+
+var sierra = kernel{32, []weight{
+	{1, 0, 5}, {2, 0, 3},
+	{-2, 1, 2}, {-1, 1, 4}, {0, 1, 5}, {1, 1, 4}, {2, 1, 2},
+	{-1, 2, 2}, {0, 2, 3}, {1, 2, 2},
+}}
+
+// diffuse quantize the pixels from left to right and top to bottom, the
+// error of every pixel is added to the next ones using k
+func diffuse(ctx context.Context, dst *image.Paletted, gray []float32, levels int, k kernel) error {
+	w, h := dst.Rect.Dx(), dst.Rect.Dy()
+	rows := progress.NewRows(progress.FromContext(ctx), h)
+	for y := 0; y < h; y++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for x := 0; x < w; x++ {
+			v := gray[y*w+x]
+			i := quantize(v, levels)
+			dst.Pix[y*dst.Stride+x] = i
+			e := (v - levelValue(i, levels)) / k.divisor
+			for _, kw := range k.weights {
+				nx, ny := x+kw.dx, y+kw.dy
+				if nx < 0 || nx >= w || ny >= h {
+					continue
+				}
+				gray[ny*w+nx] += e * kw.w
+			}
+		}
+		rows.Done(1)
+	}
+	return nil
+}
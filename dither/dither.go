@@ -0,0 +1,111 @@
+package dither
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/victorvbello/img-processing/pixelextract"
+)
+
+const (
+	NONE            = "none"
+	FLOYD_STEINBERG = "floyd-steinberg"
+	ATKINSON        = "atkinson"
+	JARVIS          = "jarvis-judice-ninke"
+	SIERRA          = "sierra"
+	BAYER_2         = "bayer2"
+	BAYER_4         = "bayer4"
+	BAYER_8         = "bayer8"
+	BLUE_NOISE      = "blue-noise"
+	MAX_LEVELS      = 256
+)
+
+// Methods return the names accepted by Gray
+func Methods() []string {
+	return []string{NONE, FLOYD_STEINBERG, ATKINSON, JARVIS, SIERRA, BAYER_2, BAYER_4, BAYER_8, BLUE_NOISE}
+}
+
+// Check return an error when method is not one of Methods or levels is not
+// between 2 and MAX_LEVELS
+func Check(method string, levels int) error {
+	if levels < 2 || levels > MAX_LEVELS {
+		return fmt.Errorf("dither: %d levels, use 2 to %d", levels, MAX_LEVELS)
+	}
+	for _, m := range Methods() {
+		if m == method {
+			return nil
+		}
+	}
+	return fmt.Errorf("dither method %q not available, use %s", method, strings.Join(Methods(), ", "))
+}
+
+// Palette return levels grays spread from black to white
+func Palette(levels int) color.Palette {
+	p := make(color.Palette, levels)
+	for i := range p {
+		p[i] = color.Gray{uint8(i * 255 / (levels - 1))}
+	}
+	return p
+}
+
+// Gray reduce the gray levels of img to levels using method, the result use
+// Palette(levels) so a 2 levels image is written as a 1-bit png
+func Gray(ctx context.Context, img image.Image, method string, levels int) (*image.Paletted, error) {
+	if err := Check(method, levels); err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	w := bounds.Dx()
+	gray := make([]float32, w*bounds.Dy())
+	pixelextract.Extract(img).Range(func(x, y int, c color.RGBA) bool {
+		gray[(y-bounds.Min.Y)*w+x-bounds.Min.X] = float32(pixelextract.ColorGrayScale(c))
+		return true
+	})
+
+	dst := image.NewPaletted(bounds, Palette(levels))
+	var err error
+	switch method {
+	case NONE:
+		err = ordered(ctx, dst, gray, levels, [][]float32{{0.5}})
+	case FLOYD_STEINBERG:
+		err = diffuse(ctx, dst, gray, levels, floydSteinberg)
+	case ATKINSON:
+		err = diffuse(ctx, dst, gray, levels, atkinson)
+	case JARVIS:
+		err = diffuse(ctx, dst, gray, levels, jarvisJudiceNinke)
+	case SIERRA:
+		err = diffuse(ctx, dst, gray, levels, sierra)
+	case BAYER_2:
+		err = ordered(ctx, dst, gray, levels, bayer(2))
+	case BAYER_4:
+		err = ordered(ctx, dst, gray, levels, bayer(4))
+	case BAYER_8:
+		err = ordered(ctx, dst, gray, levels, bayer(8))
+	case BLUE_NOISE:
+		err = ordered(ctx, dst, gray, levels, blueNoise())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// quantize return the index of the level nearest to v, v from 0 to 255
+func quantize(v float32, levels int) uint8 {
+	i := int(v*float32(levels-1)/255 + 0.5)
+	if i < 0 {
+		return 0
+	}
+	if i >= levels {
+		return uint8(levels - 1)
+	}
+	return uint8(i)
+}
+
+// levelValue return the gray value of the level i
+func levelValue(i uint8, levels int) float32 {
+	return float32(int(i) * 255 / (levels - 1))
+}
@@ -0,0 +1,180 @@
+package dither
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		method string
+		levels int
+		err    string
+	}{
+		{FLOYD_STEINBERG, 2, ""},
+		{BAYER_8, MAX_LEVELS, ""},
+		{NONE, 16, ""},
+		{ATKINSON, 1, "levels"},
+		{ATKINSON, MAX_LEVELS + 1, "levels"},
+		{"bayer3", 2, "not available"},
+		{"", 2, "not available"},
+	}
+	for _, tt := range tests {
+		err := Check(tt.method, tt.levels)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("Check(%q, %d): %v", tt.method, tt.levels, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Check(%q, %d): got error %v, want %q", tt.method, tt.levels, err, tt.err)
+		}
+	}
+}
+
+func TestPalette(t *testing.T) {
+	p := Palette(2)
+	if len(p) != 2 || p[0] != (color.Gray{0}) || p[1] != (color.Gray{255}) {
+		t.Errorf("Palette(2) = %v, want black and white", p)
+	}
+	p = Palette(MAX_LEVELS)
+	for i, c := range p {
+		if c != (color.Gray{uint8(i)}) {
+			t.Fatalf("Palette(%d)[%d] = %v", MAX_LEVELS, i, c)
+		}
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	tests := []struct {
+		v      float32
+		levels int
+		want   uint8
+	}{
+		{0, 2, 0},
+		{127, 2, 0},
+		{128, 2, 1},
+		{255, 2, 1},
+		{-40, 2, 0},
+		{300, 4, 3},
+		{85, 4, 1},
+		{200, 256, 200},
+	}
+	for _, tt := range tests {
+		if got := quantize(tt.v, tt.levels); got != tt.want {
+			t.Errorf("quantize(%v, %d) = %d, want %d", tt.v, tt.levels, got, tt.want)
+		}
+		if got := levelValue(quantize(tt.v, tt.levels), tt.levels); got != float32(Palette(tt.levels)[tt.want].(color.Gray).Y) {
+			t.Errorf("levelValue(%d, %d) = %v, not the palette gray", tt.want, tt.levels, got)
+		}
+	}
+}
+
+func TestBayer(t *testing.T) {
+	want := [][]float32{{0.5 / 4, 2.5 / 4}, {3.5 / 4, 1.5 / 4}}
+	got := bayer(2)
+	for y := range want {
+		for x := range want[y] {
+			if got[y][x] != want[y][x] {
+				t.Errorf("bayer(2)[%d][%d] = %v, want %v", y, x, got[y][x], want[y][x])
+			}
+		}
+	}
+	for _, n := range []int{2, 4, 8} {
+		checkRanks(t, "bayer", bayer(n), n)
+	}
+}
+
+func TestBlueNoise(t *testing.T) {
+	checkRanks(t, "blue noise", blueNoise(), BLUE_NOISE_SIZE)
+}
+
+// checkRanks check m is n x n and hold every threshold (r+0.5)/n² once
+func checkRanks(t *testing.T, name string, m [][]float32, n int) {
+	t.Helper()
+	if len(m) != n {
+		t.Fatalf("%s: %d rows, want %d", name, len(m), n)
+	}
+	seen := make([]bool, n*n)
+	for _, row := range m {
+		if len(row) != n {
+			t.Fatalf("%s: %d columns, want %d", name, len(row), n)
+		}
+		for _, v := range row {
+			r := int(v * float32(n*n))
+			if r < 0 || r >= n*n || seen[r] {
+				t.Fatalf("%s: threshold %v repeated or out of range", name, v)
+			}
+			seen[r] = true
+		}
+	}
+}
+
+func TestGray(t *testing.T) {
+	gradient := image.NewGray(image.Rect(0, 0, 64, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 64; x++ {
+			gradient.SetGray(x, y, color.Gray{uint8(x * 4)})
+		}
+	}
+	flat := image.NewGray(image.Rect(0, 0, 64, 64))
+	for i := range flat.Pix {
+		flat.Pix[i] = 128
+	}
+	for _, method := range Methods() {
+		for _, levels := range []int{2, 4} {
+			dst, err := Gray(context.Background(), gradient, method, levels)
+			if err != nil {
+				t.Fatalf("%s %d levels: %v", method, levels, err)
+			}
+			if len(dst.Palette) != levels || dst.Bounds() != gradient.Bounds() {
+				t.Errorf("%s %d levels: palette %d, bounds %v", method, levels, len(dst.Palette), dst.Bounds())
+			}
+			for i, p := range dst.Pix {
+				if int(p) >= levels {
+					t.Fatalf("%s %d levels: pixel %d index %d", method, levels, i, p)
+				}
+			}
+			// the black and white columns stay the same
+			for y := 0; y < 16; y++ {
+				if dst.ColorIndexAt(0, y) != 0 {
+					t.Errorf("%s %d levels: black pixel at row %d changed", method, levels, y)
+				}
+			}
+		}
+		if method == NONE {
+			continue
+		}
+		// dithering keep the average gray of a flat image
+		dst, err := Gray(context.Background(), flat, method, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var white int
+		for _, p := range dst.Pix {
+			white += int(p)
+		}
+		if ratio := float64(white) / float64(len(dst.Pix)); math.Abs(ratio-128.0/255) > 0.05 {
+			t.Errorf("%s: %.2f of a 128 gray image is white", method, ratio)
+		}
+	}
+}
+
+func TestGrayCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	for _, method := range []string{FLOYD_STEINBERG, BAYER_4} {
+		if _, err := Gray(ctx, img, method, 2); err != context.Canceled {
+			t.Errorf("%s: err = %v, want %v", method, err, context.Canceled)
+		}
+	}
+	if _, err := Gray(context.Background(), img, "bayer3", 2); err == nil {
+		t.Error("expected an error for an unknown method")
+	}
+}
@@ -0,0 +1,197 @@
+package dither
+
+import (
+	"context"
+	"image"
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/victorvbello/img-processing/progress"
+)
+
+const (
+	BLUE_NOISE_SIZE  = 64
+	BLUE_NOISE_SIGMA = 1.5
+	// BLUE_NOISE_SEED make the same blue noise texture on every run
+	BLUE_NOISE_SEED = 1
+)
+
+// ordered add the threshold of the pixel position in the tiled matrix m, from
+// 0 to 1, before the quantization
+func ordered(ctx context.Context, dst *image.Paletted, gray []float32, levels int, m [][]float32) error {
+	w, h := dst.Rect.Dx(), dst.Rect.Dy()
+	step := float32(255) / float32(levels-1)
+	rows := progress.NewRows(progress.FromContext(ctx), h)
+	for y := 0; y < h; y++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		row := m[y%len(m)]
+		for x := 0; x < w; x++ {
+			v := gray[y*w+x] + (row[x%len(row)]-0.5)*step
+			dst.Pix[y*dst.Stride+x] = quantize(v, levels)
+		}
+		rows.Done(1)
+	}
+	return nil
+}
+
+// bayer return the n x n Bayer matrix, n a power of 2, made by recursion
+// from the 2x2 one
+func bayer(n int) [][]float32 {
+	index := [][]int{{0}}
+	for size := 1; size < n; size *= 2 {
+		next := make([][]int, size*2)
+		for y := range next {
+			next[y] = make([]int, size*2)
+		}
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				v := 4 * index[y][x]
+				next[y][x] = v
+				next[y][x+size] = v + 2
+				next[y+size][x] = v + 3
+				next[y+size][x+size] = v + 1
+			}
+		}
+		index = next
+	}
+	return thresholds(index)
+}
+
+var (
+	blueNoiseOnce   sync.Once
+	blueNoiseMatrix [][]float32
+)
+
+// blueNoise return a BLUE_NOISE_SIZE square matrix made once with the
+// void-and-cluster method
+func blueNoise() [][]float32 {
+	blueNoiseOnce.Do(func() {
+		blueNoiseMatrix = thresholds(voidAndCluster(BLUE_NOISE_SIZE, BLUE_NOISE_SIGMA, rand.New(rand.NewSource(BLUE_NOISE_SEED))))
+	})
+	return blueNoiseMatrix
+}
+
+// thresholds turn the ranks 0 to n*n-1 of index into values between 0 and 1
+func thresholds(index [][]int) [][]float32 {
+	n := len(index) * len(index[0])
+	m := make([][]float32, len(index))
+	for y, row := range index {
+		m[y] = make([]float32, len(row))
+		for x, r := range row {
+			m[y][x] = (float32(r) + 0.5) / float32(n)
+		}
+	}
+	return m
+}
+
+// clusterField keep the points of a size x size toroidal binary pattern and
+// the gaussian energy every point add to the others
+type clusterField struct {
+	size   int
+	points []bool
+	energy []float64
+	filter []float64
+}
+
+func newClusterField(size int, sigma float64) *clusterField {
+	cf := &clusterField{
+		size:   size,
+		points: make([]bool, size*size),
+		energy: make([]float64, size*size),
+		filter: make([]float64, size*size),
+	}
+	for dy := 0; dy < size; dy++ {
+		for dx := 0; dx < size; dx++ {
+			// the distance wrap around the borders
+			x, y := math.Min(float64(dx), float64(size-dx)), math.Min(float64(dy), float64(size-dy))
+			cf.filter[dy*size+dx] = math.Exp(-(x*x + y*y) / (2 * sigma * sigma))
+		}
+	}
+	return cf
+}
+
+func (cf *clusterField) set(i int, on bool) {
+	cf.points[i] = on
+	sign := 1.0
+	if !on {
+		sign = -1
+	}
+	px, py := i%cf.size, i/cf.size
+	for y := 0; y < cf.size; y++ {
+		dy := (y - py + cf.size) % cf.size
+		for x := 0; x < cf.size; x++ {
+			dx := (x - px + cf.size) % cf.size
+			cf.energy[y*cf.size+x] += sign * cf.filter[dy*cf.size+dx]
+		}
+	}
+}
+
+// tightestCluster return the point with the highest energy
+func (cf *clusterField) tightestCluster() int {
+	best := -1
+	for i, on := range cf.points {
+		if on && (best < 0 || cf.energy[i] > cf.energy[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// largestVoid return the empty position with the lowest energy
+func (cf *clusterField) largestVoid() int {
+	best := -1
+	for i, on := range cf.points {
+		if !on && (best < 0 || cf.energy[i] < cf.energy[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// voidAndCluster rank the positions of a size x size matrix so any threshold
+// of it give evenly spread points without low frequencies
+func voidAndCluster(size int, sigma float64, rnd *rand.Rand) [][]int {
+	n := size * size
+	cf := newClusterField(size, sigma)
+	// a random initial pattern of a tenth of the points, moved until the
+	// tightest cluster is the largest void
+	initial := n / 10
+	for _, i := range rnd.Perm(n)[:initial] {
+		cf.set(i, true)
+	}
+	for {
+		cluster := cf.tightestCluster()
+		cf.set(cluster, false)
+		void := cf.largestVoid()
+		cf.set(void, true)
+		if void == cluster {
+			break
+		}
+	}
+	pattern := append([]bool(nil), cf.points...)
+	energy := append([]float64(nil), cf.energy...)
+
+	rank := make([]int, n)
+	// the points of the pattern get the lowest ranks, the tightest first
+	for ones := initial; ones > 0; ones-- {
+		i := cf.tightestCluster()
+		cf.set(i, false)
+		rank[i] = ones - 1
+	}
+	// the voids get the next ones
+	cf.points, cf.energy = pattern, energy
+	for ones := initial; ones < n; ones++ {
+		i := cf.largestVoid()
+		cf.set(i, true)
+		rank[i] = ones
+	}
+
+	index := make([][]int, size)
+	for y := range index {
+		index[y] = rank[y*size : (y+1)*size]
+	}
+	return index
+}
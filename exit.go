@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/victorvbello/img-processing/imagefilter"
+)
+
+// exit status of the cli, 1 is any other error
+const (
+	EXIT_ERROR              = 1
+	EXIT_UNSUPPORTED_FORMAT = 3
+	EXIT_DECODE             = 4
+	EXIT_ENCODE             = 5
+	EXIT_TIMEOUT            = 124
+	EXIT_INTERRUPTED        = 130
+)
+
+// errorList aggregate the errors of the images processed by one command
+type errorList []error
+
+func (l errorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors:\n\t%s", len(l), strings.Join(msgs, "\n\t"))
+}
+
+// err return nil for an empty list and the error itself for a single one
+func (l errorList) err() error {
+	switch len(l) {
+	case 0:
+		return nil
+	case 1:
+		return l[0]
+	}
+	return l
+}
+
+// inputError add the input path to err unless it is a *imagefilter.FileError
+// that already carry it
+func inputError(path string, err error) error {
+	var fe *imagefilter.FileError
+	if errors.As(err, &fe) {
+		return err
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
+
+// canceled report whether err come from SIGINT or --timeout, the remaining
+// images are not processed
+func canceled(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// exitCode map err to the exit status, an errorList use the status of the
+// cancellation if any, otherwise the one shared by all its errors or
+// EXIT_ERROR when they differ
+func exitCode(err error) int {
+	if l, ok := err.(errorList); ok {
+		for _, err := range l {
+			if canceled(err) {
+				return exitCode(err)
+			}
+		}
+		code := exitCode(l[0])
+		for _, err := range l[1:] {
+			if exitCode(err) != code {
+				return EXIT_ERROR
+			}
+		}
+		return code
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return EXIT_TIMEOUT
+	case errors.Is(err, context.Canceled):
+		return EXIT_INTERRUPTED
+	case errors.Is(err, imagefilter.ErrUnsupportedFormat):
+		return EXIT_UNSUPPORTED_FORMAT
+	case errors.Is(err, imagefilter.ErrDecode):
+		return EXIT_DECODE
+	case errors.Is(err, imagefilter.ErrEncode):
+		return EXIT_ENCODE
+	}
+	return EXIT_ERROR
+}
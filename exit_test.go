@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/victorvbello/img-processing/imagefilter"
+)
+
+func TestExitCode(t *testing.T) {
+	decode := &imagefilter.FileError{Kind: imagefilter.ErrDecode, Path: "in.png", Err: errors.New("bad header")}
+	encode := &imagefilter.FileError{Kind: imagefilter.ErrEncode, Path: "out.png", Err: errors.New("disk full")}
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"other", errors.New("boom"), EXIT_ERROR},
+		{"unsupported format", fmt.Errorf("%w %q", imagefilter.ErrUnsupportedFormat, "webp"), EXIT_UNSUPPORTED_FORMAT},
+		{"decode", decode, EXIT_DECODE},
+		{"wrapped decode", inputError("in.png", fmt.Errorf("grayscale: %w", decode)), EXIT_DECODE},
+		{"encode", encode, EXIT_ENCODE},
+		{"timeout", fmt.Errorf("step 0: %w", context.DeadlineExceeded), EXIT_TIMEOUT},
+		{"interrupted", context.Canceled, EXIT_INTERRUPTED},
+		{"list of the same kind", errorList{decode, decode}, EXIT_DECODE},
+		{"list of different kinds", errorList{decode, encode}, EXIT_ERROR},
+		{"list with a timeout", errorList{decode, context.DeadlineExceeded, encode}, EXIT_TIMEOUT},
+		{"list with an interruption", errorList{errors.New("boom"), context.Canceled}, EXIT_INTERRUPTED},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	var l errorList
+	if err := l.err(); err != nil {
+		t.Errorf("empty list err = %v, want nil", err)
+	}
+	first := errors.New("first")
+	l = append(l, first)
+	if err := l.err(); err != first {
+		t.Errorf("single error list err = %v, want %v", err, first)
+	}
+	l = append(l, errors.New("second"))
+	if got, want := l.err().Error(), "2 errors:\n\tfirst\n\tsecond"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestInputError(t *testing.T) {
+	fe := &imagefilter.FileError{Kind: imagefilter.ErrDecode, Path: "in.png", Err: errors.New("bad header")}
+	if err := inputError("in.png", fe); err != fe {
+		t.Errorf("inputError added the path again: %v", err)
+	}
+	if got, want := inputError("in.png", errors.New("boom")).Error(), "in.png: boom"; got != want {
+		t.Errorf("inputError = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package experiment
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"sort"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// FontCharacterInfo measure the characters of s drawn with the .ttf/.otf font
+// of path at size points (72 dpi), every character must have a glyph
+func FontCharacterInfo(path string, size float64, s string) (CharacterInfo, error) {
+	if size <= 0 {
+		return CharacterInfo{}, fmt.Errorf("font size %v must be positive", size)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return CharacterInfo{}, fmt.Errorf("read-font %w", err)
+	}
+	f, err := opentype.Parse(b)
+	if err != nil {
+		return CharacterInfo{}, fmt.Errorf("parse-font %s: %w", path, err)
+	}
+	var buf sfnt.Buffer
+	for _, c := range s {
+		i, err := f.GlyphIndex(&buf, c)
+		if err != nil {
+			return CharacterInfo{}, fmt.Errorf("font %s: %w", path, err)
+		}
+		if i == 0 {
+			return CharacterInfo{}, fmt.Errorf("font %s has no glyph for %q", path, c)
+		}
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return CharacterInfo{}, fmt.Errorf("font-face %s: %w", path, err)
+	}
+	defer face.Close()
+	return FaceCharacterInfo(face, s)
+}
+
+// FaceCharacterInfo draw every character of s with face in a cell as wide as
+// the widest character and as tall as the line, the GrayPercentage is the
+// part of the cell covered by the glyph. The table is sorted from the
+// densest character to the lightest one
+func FaceCharacterInfo(face font.Face, s string) (CharacterInfo, error) {
+	chars := []rune(s)
+	if len(chars) == 0 {
+		return CharacterInfo{}, fmt.Errorf("character info without characters")
+	}
+	metrics := face.Metrics()
+	var advance fixed.Int26_6
+	for _, c := range chars {
+		a, ok := face.GlyphAdvance(c)
+		if !ok {
+			return CharacterInfo{}, fmt.Errorf("face has no glyph for %q", c)
+		}
+		if a > advance {
+			advance = a
+		}
+	}
+	width, height := advance.Ceil(), (metrics.Ascent + metrics.Descent).Ceil()
+	if width < 1 || height < 1 {
+		return CharacterInfo{}, fmt.Errorf("empty character cell %dx%d", width, height)
+	}
+	maxColorValue := MAX_COLOR_VALUE * width * height
+
+	cell := image.NewAlpha(image.Rect(0, 0, width, height))
+	characterWeight := make([]CharacterMetadata, len(chars))
+	for i, c := range chars {
+		for p := range cell.Pix {
+			cell.Pix[p] = 0
+		}
+		d := font.Drawer{
+			Dst:  cell,
+			Src:  image.Opaque,
+			Face: face,
+			Dot:  fixed.Point26_6{Y: metrics.Ascent},
+		}
+		d.DrawString(string(c))
+		var weight int
+		for _, a := range cell.Pix {
+			weight += int(a)
+		}
+		characterWeight[i] = CharacterMetadata{
+			Char:           string(c),
+			GrayPercentage: float32(weight*100) / float32(maxColorValue),
+		}
+	}
+	sort.Stable(byGrayPercentage(characterWeight))
+
+	return CharacterInfo{
+		StrBase:       s,
+		ColorFactor:   float32(maxColorValue / len(chars)),
+		MaxColorValue: maxColorValue,
+		BaseWidth:     width,
+		BaseHeight:    height,
+		CharacterData: characterWeight,
+	}, nil
+}
@@ -1,23 +1,18 @@
 package experiment
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"image"
-	"image/color"
-	"log"
 	"os"
 	"path/filepath"
-	"sort"
-	"sync"
 	"time"
 
-	"golang.org/x/image/font"
 	"golang.org/x/image/font/basicfont"
-	"golang.org/x/image/math/fixed"
 
+	"github.com/victorvbello/img-processing/ascii"
 	"github.com/victorvbello/img-processing/imagefilter"
-	"github.com/victorvbello/img-processing/pixelextract"
 )
 
 type CharacterInfo struct {
@@ -40,146 +35,98 @@ func (wp byGrayPercentage) Len() int           { return len(wp) }
 func (wp byGrayPercentage) Swap(i, j int)      { wp[i], wp[j] = wp[j], wp[i] }
 func (wp byGrayPercentage) Less(i, j int) bool { return wp[i].GrayPercentage > wp[j].GrayPercentage } // DESC
 
-const MAX_COLOR_VALUE = 255
-
-func makeCharanterImg(basePath string, alias string, char string, width int, height int) (string, error) {
-	finalImgName := basePath + alias + "_" + char + ".png"
-	img := image.NewRGBA(image.Rect(0, 0, width, height))
-	x, y := 2, 10
-	col := color.Black
-
-	point := fixed.Point26_6{X: fixed.Int26_6(x * 64), Y: fixed.Int26_6(y * 64)}
+const (
+	MAX_COLOR_VALUE = 255
+	// DEFAULT_WEIGHT_FILE is the table written by character-pixel-weight
+	DEFAULT_WEIGHT_FILE = "./files/unpublished/matrix/charts_weight.txt"
+)
 
-	d := &font.Drawer{
-		Dst:  img,
-		Src:  image.NewUniform(col),
-		Face: basicfont.Face7x13,
-		Dot:  point,
+// CharacterPixelTypeCountMakeFile measure every character of s drawn with
+// basicfont.Face7x13 and write the sorted table to outFileName
+func CharacterPixelTypeCountMakeFile(s string, outFileName string) error {
+	for _, c := range s {
+		if !bitmapGlyph(basicfont.Face7x13, c) {
+			return fmt.Errorf("the 7x13 bitmap font has no glyph for %q, use --font", c)
+		}
 	}
-	d.DrawString(char)
-
-	_, err := imagefilter.EncodeIMG(img, finalImgName)
+	ci, err := FaceCharacterInfo(basicfont.Face7x13, s)
 	if err != nil {
-		return "", err
+		return err
 	}
-	return finalImgName, nil
+	return SaveCharacterInfo(ci, outFileName)
 }
 
-func characterColorGrayScaleWeight(xp []pixelextract.PixelColor) uint32 {
-	var weight uint32
-	for _, p := range xp {
-		weight += p.ColorGrayScale()
+// bitmapGlyph report whether r is in the ranges of face, Glyph draw the
+// replacement character for the others
+func bitmapGlyph(face *basicfont.Face, r rune) bool {
+	for _, rng := range face.Ranges {
+		if r >= rng.Low && r < rng.High {
+			return true
+		}
 	}
-	return weight
+	return false
 }
 
-func characterColorWeight(xp []pixelextract.PixelColor) uint32 {
-	var weight uint32
-	for _, p := range xp {
-		weight += p.ColorWeight()
+// SaveCharacterInfo write the table to path as json, LoadCharacterInfo read
+// it back
+func SaveCharacterInfo(ci CharacterInfo, path string) error {
+	fileBody, err := json.Marshal(ci)
+	if err != nil {
+		return fmt.Errorf("json-marshal %w", err)
 	}
-	return weight
-}
-
-func CharacterPixelTypeCountMakeFile(s string) {
-	var wg sync.WaitGroup
-	characterWeight := []CharacterMetadata{}
-	logger := make(chan string)
-	fileNameList := make(chan CharacterMetadata)
-	basePath := "./files/unpublished/matrix/"
-	baseWidth := 10
-	baseHeight := 12
-	for taskID, c := range s {
-		wg.Add(1)
-		go func(id int, char string) {
-			fileName, err := makeCharanterImg(basePath, "character", char, baseWidth, baseHeight)
-			if err != nil {
-				logger <- fmt.Sprintf("MakeCharanterImg, task: %d error %v", id, err)
-				return
-			}
-			go func() {
-				fileNameList <- CharacterMetadata{char, fileName, 0}
-			}()
-			wg.Done()
-		}(taskID, string(c))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create-dir %w", err)
 	}
-
-	go func() {
-		wg.Wait()
-		close(logger)
-	}()
-
-	for l := range logger {
-		fmt.Println(l)
+	if err := os.WriteFile(path, fileBody, 0644); err != nil {
+		return fmt.Errorf("create-file %w", err)
 	}
-	maxColorValue := MAX_COLOR_VALUE * baseWidth * baseHeight
-	colorFactor := float32(maxColorValue / len(s))
-	for i := 0; i < len(s); i++ {
-		fileData := <-fileNameList
-		img, err := imagefilter.DecodeImg(fileData.Filename)
-		if err != nil {
-			log.Fatal(fmt.Errorf("decode-png-file %w", err))
+	return nil
+}
+
+// CharacterInfoFromRamp make a CharacterInfo from a ramp of characters sorted
+// from the densest to the lightest, without measuring glyphs
+func CharacterInfoFromRamp(ramp string) CharacterInfo {
+	chars := []rune(ramp)
+	characterData := make([]CharacterMetadata, len(chars))
+	for i, c := range chars {
+		characterData[i] = CharacterMetadata{
+			Char:           string(c),
+			GrayPercentage: float32(100*(len(chars)-i)) / float32(len(chars)),
 		}
-		xp := pixelextract.ExtractPixelFromImg(img)
-		fileData.GrayPercentage = float32(characterColorWeight(xp)*100) / float32(maxColorValue)
-		characterWeight = append(characterWeight, fileData)
 	}
-	sort.Sort(byGrayPercentage(characterWeight))
+	return CharacterInfo{
+		StrBase:       ramp,
+		MaxColorValue: MAX_COLOR_VALUE,
+		CharacterData: characterData,
+	}
+}
 
-	fileBody, err := json.Marshal(CharacterInfo{
-		StrBase:       s,
-		ColorFactor:   colorFactor,
-		MaxColorValue: maxColorValue,
-		BaseWidth:     baseWidth,
-		BaseHeight:    baseHeight,
-		CharacterData: characterWeight})
-	if err != nil {
-		log.Fatal(fmt.Errorf("json-marshal %w", err))
+// Mapper return the characters of the table as an ascii.RampMapper, the
+// table is sorted from the densest character to the lightest one
+func (ci CharacterInfo) Mapper() (ascii.RampMapper, error) {
+	if len(ci.CharacterData) == 0 {
+		return ascii.RampMapper{}, errors.New("character info without characters")
 	}
-	outFileName := basePath + "charts_weight.txt"
-	outFile, _ := os.Create(outFileName)
-	if _, err := outFile.Write(fileBody); err != nil {
-		log.Fatal(fmt.Errorf("create-file %w", err))
+	chars := make([]string, len(ci.CharacterData))
+	for i, cd := range ci.CharacterData {
+		chars[i] = cd.Char
 	}
-	outFile.Close()
+	return ascii.RampMapper{Chars: chars}, nil
 }
 
-func CharacterScaleTxtFile(fi *imagefilter.FilterImg, id int, chartInfo CharacterInfo) string {
-	var currentY int = -1
-	outFile, _ := os.Create(fi.GetAlias() + "_character.txt")
-	s := time.Now()
-	for _, p := range fi.GetXp() {
-		pixelGrayScaleWight := p.ColorGrayScale()
-		charLent := len(chartInfo.CharacterData)
-		colorRageIndex := float32(pixelGrayScaleWight*uint32(charLent-1)) / float32(MAX_COLOR_VALUE)
-		charIndex := int(colorRageIndex)
-
-		if charIndex >= charLent {
-			err := fmt.Errorf("charIndex: %d, not fount in characterData", charIndex)
-			log.Fatal(err)
-			fi.AddLog(err.Error())
-		}
-		defaultSpace := ""
-		currentValue := defaultSpace + chartInfo.CharacterData[charIndex].Char + defaultSpace
-		if currentY != p.Y {
-			currentY = p.Y
-			if _, err := outFile.Write([]byte("\n" + currentValue)); err != nil {
-				log.Fatal(err)
-				fi.AddLog(err.Error())
-			}
-			continue
-		}
-		if _, err := outFile.Write([]byte(currentValue)); err != nil {
-			log.Fatal(err)
-			fi.AddLog(err.Error())
-		}
+// CharacterScaleGrid return a cell per pixel using the characters of
+// chartInfo
+func CharacterScaleGrid(ctx context.Context, fi *imagefilter.FilterImg, id int, chartInfo CharacterInfo) (*ascii.Grid, error) {
+	m, err := chartInfo.Mapper()
+	if err != nil {
+		return nil, fmt.Errorf("character-scale: %w", err)
 	}
-	outFile.Close()
-	e := time.Since(s)
-	fi.AddLog(fmt.Sprintf("character-scale, task: %d total create txt => %v", id, e))
-	path, err := os.Getwd()
+	s := time.Now()
+	g, err := ascii.FromPixels(ctx, fi.GetPixels(), m)
 	if err != nil {
-		fi.AddLog(fmt.Sprintf("character-scale, task: %d error %v", id, err))
+		return nil, fmt.Errorf("character-scale %w", err)
 	}
-	return filepath.Join(path, outFile.Name())
+	e := time.Since(s)
+	fi.AddLog(fmt.Sprintf("character-scale, task: %d total create grid => %v", id, e))
+	return g, nil
 }
@@ -0,0 +1,40 @@
+package experiment
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCharacterPixelTypeCountMakeFile(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "weights.json")
+	if err := CharacterPixelTypeCountMakeFile("a/b@. ", out); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "weights.json" {
+		t.Errorf("files written next to the table: %v", files)
+	}
+	ci, err := LoadCharacterInfo(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ci.CharacterData) != 6 || ci.BaseWidth != 7 || ci.BaseHeight != 13 {
+		t.Errorf("got %d characters of %dx%d", len(ci.CharacterData), ci.BaseWidth, ci.BaseHeight)
+	}
+	if first, last := ci.CharacterData[0], ci.CharacterData[5]; first.Char != "@" || last.Char != " " || last.GrayPercentage != 0 {
+		t.Errorf("table not sorted from @ to space: %v", ci.CharacterData)
+	}
+
+	if err := CharacterPixelTypeCountMakeFile("", out); err == nil {
+		t.Error("expected an error for an empty charset")
+	}
+	if err := CharacterPixelTypeCountMakeFile("a中", out); err == nil || !strings.Contains(err.Error(), "中") {
+		t.Errorf("got error %v, want the missing character", err)
+	}
+}
@@ -0,0 +1,115 @@
+package experiment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/ioutil"
+
+	"github.com/victorvbello/img-processing/imagefilter"
+	"github.com/victorvbello/img-processing/imagetransforms"
+	"github.com/victorvbello/img-processing/pixelextract"
+	"github.com/victorvbello/img-processing/progress"
+)
+
+// LoadCharacterInfo read a weight table made by CharacterPixelTypeCountMakeFile
+func LoadCharacterInfo(path string) (CharacterInfo, error) {
+	var charInfo CharacterInfo
+	byteValue, err := ioutil.ReadFile(path)
+	if err != nil {
+		return charInfo, err
+	}
+	err = json.Unmarshal(byteValue, &charInfo)
+	return charInfo, err
+}
+
+// CharacterScale resize the image by Scale percent and draw it using the
+// characters of Info, the output keeps the canvas size of the original image
+type CharacterScale struct {
+	Alias string
+	Info  CharacterInfo
+	Scale int
+	imagefilter.TextStyle
+}
+
+// Validate check that Info has characters and that all of them can be drawn
+func (cs CharacterScale) Validate() error {
+	m, err := cs.Info.Mapper()
+	if err != nil {
+		return err
+	}
+	return imagefilter.CheckTextGlyphs(m.Chars)
+}
+
+func (cs CharacterScale) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	if err := cs.Validate(); err != nil {
+		return nil, err
+	}
+	resizeImg, err := imagetransforms.Resize(ctx, img, cs.Scale)
+	if err != nil {
+		return nil, err
+	}
+	fi := imagefilter.NewFilterImg(cs.Alias, img, pixelextract.Extract(resizeImg), 0, progress.FromContext(ctx))
+	g, err := CharacterScaleGrid(ctx, fi, 0, cs.Info)
+	if err != nil {
+		return nil, err
+	}
+	return fi.MakeFromGrid(ctx, g, cs.TextStyle)
+}
+
+type Infinite struct {
+	Percentage int
+}
+
+func (inf Infinite) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	return ImgInfinite(ctx, img, inf.Percentage)
+}
+
+// InfiniteAnimation zoom into the layers of Infinite, Frames are made between
+// two layers and the result is an *imagefilter.Animation
+type InfiniteAnimation struct {
+	Percentage int
+	Frames     int
+	Delay      int
+	LoopCount  int
+}
+
+func (ia InfiniteAnimation) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	if ia.Percentage < 1 || ia.Frames < 1 {
+		return nil, fmt.Errorf("infinite animation: percentage %d and frames %d must be positive", ia.Percentage, ia.Frames)
+	}
+	frames, err := ImgInfiniteFrames(ctx, img, ia.Percentage, ia.Frames)
+	if err != nil {
+		return nil, err
+	}
+	return imagefilter.NewAnimation(frames, ia.Delay, ia.LoopCount), nil
+}
+
+type InfiniteSpiral struct {
+	Angle int
+}
+
+func (is InfiniteSpiral) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	return ImgInfiniteSpiral(ctx, img, is.Angle)
+}
+
+// InfiniteSpiralAnimation turn the layers of InfiniteSpiral a full circle in
+// Frames steps, the result is an *imagefilter.Animation
+type InfiniteSpiralAnimation struct {
+	Angle     int
+	Frames    int
+	Delay     int
+	LoopCount int
+}
+
+func (isa InfiniteSpiralAnimation) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	if isa.Angle < 1 || isa.Frames < 1 {
+		return nil, fmt.Errorf("infinite spiral animation: angle %d and frames %d must be positive", isa.Angle, isa.Frames)
+	}
+	frames, err := ImgInfiniteSpiralFrames(ctx, img, isa.Angle, isa.Frames)
+	if err != nil {
+		return nil, err
+	}
+	return imagefilter.NewAnimation(frames, isa.Delay, isa.LoopCount), nil
+}
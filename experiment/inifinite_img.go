@@ -1,23 +1,53 @@
 package experiment
 
 import (
+	"context"
+	"fmt"
 	"image"
 	"image/draw"
 	"sync"
+	"sync/atomic"
 
+	xdraw "golang.org/x/image/draw"
+
+	"github.com/victorvbello/img-processing/imagefilter"
 	"github.com/victorvbello/img-processing/imagetransforms"
+	"github.com/victorvbello/img-processing/progress"
 )
 
 type resizeImgItem struct {
 	index int
 	img   image.Image
+	err   error
 }
 
 func center(rect image.Rectangle) image.Point {
 	return image.Point{(rect.Max.X - rect.Min.X) / 2, (rect.Max.Y - rect.Min.Y) / 2}
 }
 
-func ImgInfinite(img image.Image, percentage int) image.Image {
+// MAX_LAYER_STEP is the highest percentage of Infinite and angle of
+// InfiniteSpiral, the layers are resized from 1 to 99 percent
+const MAX_LAYER_STEP = 99
+
+// CheckLayerStep return an error when step, the percentage or the angle
+// between two layers, is not between 1 and MAX_LAYER_STEP
+func CheckLayerStep(name string, step int) error {
+	if step < 1 || step > MAX_LAYER_STEP {
+		return fmt.Errorf("%s %d out of range 1 to %d", name, step, MAX_LAYER_STEP)
+	}
+	return nil
+}
+
+// layerCount return the number of layers resized from 1 to 99 percent every
+// step percent
+func layerCount(step int) int {
+	return (MAX_LAYER_STEP-1)/step + 1
+}
+
+func ImgInfinite(ctx context.Context, img image.Image, percentage int) (image.Image, error) {
+	if err := CheckLayerStep("infinite percentage", percentage); err != nil {
+		return nil, err
+	}
 	bounds := img.Bounds()
 	resultImg := image.NewRGBA(bounds)
 	centerBounds := center(bounds)
@@ -26,15 +56,15 @@ func ImgInfinite(img image.Image, percentage int) image.Image {
 	var wg sync.WaitGroup
 	var countImg int
 
-	imgX = make([]image.Image, int(100/percentage))
+	imgX = make([]image.Image, layerCount(percentage))
 
 	resizeChan := make(chan resizeImgItem)
 	draw.Draw(resultImg, bounds, img, image.ZP, draw.Src)
 	for i := 1; i < 100; {
 		wg.Add(1)
 		go func(fact int, imgIndex int) {
-			resizeImg := imagetransforms.Resize(img, fact)
-			resizeChan <- resizeImgItem{imgIndex, resizeImg}
+			resizeImg, err := imagetransforms.Resize(ctx, img, fact)
+			resizeChan <- resizeImgItem{imgIndex, resizeImg, err}
 			wg.Done()
 		}(i, countImg)
 		i += percentage
@@ -46,38 +76,51 @@ func ImgInfinite(img image.Image, percentage int) image.Image {
 		close(resizeChan)
 	}()
 
-	for ri := range resizeChan {
-		imgX[ri.index] = ri.img
+	if err := collectLayers(resizeChan, imgX); err != nil {
+		return nil, err
 	}
 	for _, img := range imgX {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		resizeBounds := img.Bounds()
 		newRect := image.Rect(0, 0, resizeBounds.Dx(), resizeBounds.Dy())
 		newRect = newRect.Add(centerBounds.Sub(image.Pt(newRect.Dx()/2, newRect.Dy()/2)))
 
 		draw.Draw(resultImg, newRect, img, image.ZP, draw.Src)
 	}
-	return resultImg
+	return resultImg, nil
 }
 
-func ImgInfiniteSpiral(img image.Image, angle int) image.Image {
+// collectLayers store the resized layers by index and return the first error
+func collectLayers(resizeChan <-chan resizeImgItem, imgX []image.Image) error {
+	var err error
+	for ri := range resizeChan {
+		if ri.err != nil && err == nil {
+			err = ri.err
+		}
+		imgX[ri.index] = ri.img
+	}
+	return err
+}
+
+func spiralLayers(ctx context.Context, img image.Image, angle int) ([]image.Image, error) {
+	if err := CheckLayerStep("infinite spiral angle", angle); err != nil {
+		return nil, err
+	}
 	var imgX []image.Image
 	var wg sync.WaitGroup
 	var count int
 
-	imgX = make([]image.Image, int(100/angle))
-
-	bounds := img.Bounds()
-	resultImg := image.NewRGBA(bounds)
-
-	draw.Draw(resultImg, bounds, img, image.ZP, draw.Src)
+	imgX = make([]image.Image, layerCount(angle))
 
 	resizeChan := make(chan resizeImgItem)
 
 	for i := 1; i < 100; {
 		wg.Add(1)
 		go func(fact int, xIndex int) {
-			resizeImg := imagetransforms.Resize(img, fact)
-			resizeChan <- resizeImgItem{xIndex, resizeImg}
+			resizeImg, err := imagetransforms.Resize(ctx, img, fact)
+			resizeChan <- resizeImgItem{xIndex, resizeImg, err}
 			wg.Done()
 		}(i, count)
 		count++
@@ -88,13 +131,125 @@ func ImgInfiniteSpiral(img image.Image, angle int) image.Image {
 		close(resizeChan)
 	}()
 
-	for ri := range resizeChan {
-		imgX[ri.index] = ri.img
+	if err := collectLayers(resizeChan, imgX); err != nil {
+		return nil, err
 	}
+	return imgX, nil
+}
 
+// spiralCompose rotate every layer over img, offset is added to the angle of
+// all the layers
+func spiralCompose(ctx context.Context, img image.Image, imgX []image.Image, angle int, offset float64) (image.Image, error) {
+	bounds := img.Bounds()
+	baseImg := image.NewRGBA(bounds)
+
+	draw.Draw(baseImg, bounds, img, image.ZP, draw.Src)
+
+	o := progress.FromContext(ctx)
+	var resultImg image.Image = baseImg
+	var err error
 	for i, img := range imgX {
-		resultImg = imagetransforms.Rotate(resultImg, img, float64(i+angle)).(*image.RGBA)
+		resultImg, err = imagetransforms.Rotate(ctx, resultImg, img, float64(i+angle)+offset)
+		if err != nil {
+			return nil, err
+		}
+		progress.Notify(o, progress.Event{
+			Kind:    progress.PROGRESS,
+			Percent: progress.Percent(i+1, len(imgX)),
+			Message: fmt.Sprintf("layer %d/%d", i+1, len(imgX)),
+		})
+	}
+
+	return resultImg, nil
+}
+
+func ImgInfiniteSpiral(ctx context.Context, img image.Image, angle int) (image.Image, error) {
+	imgX, err := spiralLayers(ctx, img, angle)
+	if err != nil {
+		return nil, err
+	}
+	return spiralCompose(ctx, img, imgX, angle, 0)
+}
+
+// ImgInfiniteSpiralFrames return frames of ImgInfiniteSpiral whose layers
+// turn a full circle, the angle offset advance 360/steps degrees per frame
+func ImgInfiniteSpiralFrames(ctx context.Context, img image.Image, angle int, steps int) ([]image.Image, error) {
+	imgX, err := spiralLayers(ctx, img, angle)
+	if err != nil {
+		return nil, err
+	}
+	return makeFrames(ctx, steps, func(frameCtx context.Context, frameIndex int) (image.Image, error) {
+		return spiralCompose(frameCtx, img, imgX, angle, 360*float64(frameIndex)/float64(steps))
+	})
+}
+
+// makeFrames call frame for every index on up to imagefilter.Workers(ctx)
+// goroutines and return the first error, the progress is the number of
+// frames done
+func makeFrames(ctx context.Context, steps int, frame func(frameCtx context.Context, frameIndex int) (image.Image, error)) ([]image.Image, error) {
+	var wg sync.WaitGroup
+	var framesDone int32
+	o := progress.FromContext(ctx)
+	frameCtx := progress.WithObserver(ctx, nil)
+	frames := make([]image.Image, steps)
+	errs := make([]error, steps)
+	sem := make(chan struct{}, imagefilter.Workers(ctx))
+	for i := 0; i < steps; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(frameIndex int) {
+			defer func() { <-sem }()
+			frames[frameIndex], errs[frameIndex] = frame(frameCtx, frameIndex)
+			if errs[frameIndex] == nil {
+				done := int(atomic.AddInt32(&framesDone, 1))
+				progress.Notify(o, progress.Event{
+					Kind:    progress.PROGRESS,
+					Percent: progress.Percent(done, steps),
+					Message: fmt.Sprintf("frame %d/%d", done, steps),
+				})
+			}
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return frames, nil
+}
+
+// infiniteFrame draw the layers of ImgInfinite shifted by t of a step, t=1
+// give the same image as t=0 so the frames can loop
+func infiniteFrame(ctx context.Context, img image.Image, percentage int, t float64) (image.Image, error) {
+	bounds := img.Bounds()
+	resultImg := image.NewRGBA(bounds)
+	centerBounds := center(bounds)
+	for i := -1; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		reduction := 1 + (float64(i)-t)*float64(percentage)
+		if reduction >= 100 {
+			break
+		}
+		scale := (100 - reduction) / 100
+		newRect := image.Rect(0, 0, int(float64(bounds.Dx())*scale), int(float64(bounds.Dy())*scale))
+		if newRect.Empty() {
+			break
+		}
+		newRect = newRect.Add(centerBounds.Sub(image.Pt(newRect.Dx()/2, newRect.Dy()/2)))
+		xdraw.ApproxBiLinear.Scale(resultImg, newRect, img, bounds, draw.Src, nil)
 	}
+	return resultImg, nil
+}
 
-	return resultImg
+// ImgInfiniteFrames return the frames of a zoom into the layers of
+// ImgInfinite, steps frames are made between two consecutive layers and the
+// last frame connect with the first one
+func ImgInfiniteFrames(ctx context.Context, img image.Image, percentage int, steps int) ([]image.Image, error) {
+	return makeFrames(ctx, steps, func(frameCtx context.Context, frameIndex int) (image.Image, error) {
+		return infiniteFrame(frameCtx, img, percentage, float64(frameIndex)/float64(steps))
+	})
 }
@@ -0,0 +1,92 @@
+package experiment
+
+import (
+	"context"
+	"errors"
+	"image"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/victorvbello/img-processing/imagefilter"
+)
+
+func TestInfiniteLayerSteps(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	ctx := context.Background()
+	for _, step := range []int{1, 7, 33, 98, 99} {
+		if _, err := ImgInfinite(ctx, img, step); err != nil {
+			t.Errorf("infinite percentage %d: %v", step, err)
+		}
+		if _, err := ImgInfiniteSpiral(ctx, img, step); err != nil {
+			t.Errorf("infinite spiral angle %d: %v", step, err)
+		}
+	}
+	for _, step := range []int{-5, 0, 100} {
+		if _, err := ImgInfinite(ctx, img, step); err == nil {
+			t.Errorf("infinite percentage %d: expected an error", step)
+		}
+		if _, err := ImgInfiniteSpiral(ctx, img, step); err == nil {
+			t.Errorf("infinite spiral angle %d: expected an error", step)
+		}
+	}
+}
+
+func TestLayerCount(t *testing.T) {
+	tests := []struct{ step, layers int }{
+		{1, 99}, {5, 20}, {7, 15}, {33, 3}, {98, 2}, {99, 1},
+	}
+	for _, tt := range tests {
+		n := 0
+		for i := 1; i < 100; i += tt.step {
+			n++
+		}
+		if got := layerCount(tt.step); got != tt.layers || got != n {
+			t.Errorf("layerCount(%d) = %d, want %d", tt.step, got, tt.layers)
+		}
+	}
+}
+
+func TestMakeFramesWorkers(t *testing.T) {
+	for _, workers := range []int{1, 3} {
+		var running, peak int32
+		ctx := imagefilter.WithWorkers(context.Background(), workers)
+		frames, err := makeFrames(ctx, 10, func(frameCtx context.Context, frameIndex int) (image.Image, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return image.NewRGBA(image.Rect(0, 0, frameIndex+1, 1)), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if peak > int32(workers) {
+			t.Errorf("workers %d: %d frames made at the same time", workers, peak)
+		}
+		for i, f := range frames {
+			if f.Bounds().Dx() != i+1 {
+				t.Errorf("workers %d: frame %d out of order", workers, i)
+			}
+		}
+	}
+}
+
+func TestMakeFramesError(t *testing.T) {
+	errFrame := errors.New("frame failed")
+	ctx := imagefilter.WithWorkers(context.Background(), 2)
+	_, err := makeFrames(ctx, 5, func(frameCtx context.Context, frameIndex int) (image.Image, error) {
+		if frameIndex == 3 {
+			return nil, errFrame
+		}
+		return image.NewRGBA(image.Rect(0, 0, 1, 1)), nil
+	})
+	if err != errFrame {
+		t.Errorf("err = %v, want %v", err, errFrame)
+	}
+}
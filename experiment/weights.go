@@ -0,0 +1,64 @@
+package experiment
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// the tables are made with
+// character-pixel-weight --font Go-Mono.ttf --size 16 --charset <ramp> --out weights/<name>.json
+//
+//go:embed weights/*.json
+var weightTables embed.FS
+
+const (
+	// DEFAULT_WEIGHT_TABLE is the embedded table used when no table is chosen
+	DEFAULT_WEIGHT_TABLE = "standard"
+	WEIGHT_TABLE_DIR     = "weights/"
+)
+
+// WeightTables return the names of the embedded tables sorted: blocks (block
+// elements), dense (70 characters ramp) and standard (@%#*+=-:. ramp)
+func WeightTables() []string {
+	entries, _ := weightTables.ReadDir(strings.TrimSuffix(WEIGHT_TABLE_DIR, "/"))
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WeightTable return the embedded table called name
+func WeightTable(name string) (CharacterInfo, error) {
+	var charInfo CharacterInfo
+	byteValue, err := weightTables.ReadFile(WEIGHT_TABLE_DIR + name + ".json")
+	if err != nil {
+		return charInfo, fmt.Errorf("weight table %q not available, use %s or a file path", name, strings.Join(WeightTables(), ", "))
+	}
+	err = json.Unmarshal(byteValue, &charInfo)
+	return charInfo, err
+}
+
+// LoadWeights return the embedded table called nameOrPath or read the file
+// of that path, an empty nameOrPath is DEFAULT_WEIGHT_TABLE
+func LoadWeights(nameOrPath string) (CharacterInfo, error) {
+	if nameOrPath == "" {
+		nameOrPath = DEFAULT_WEIGHT_TABLE
+	}
+	for _, name := range WeightTables() {
+		if name == nameOrPath {
+			return WeightTable(name)
+		}
+	}
+	charInfo, err := LoadCharacterInfo(nameOrPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return charInfo, fmt.Errorf("weight table %q not available, use %s or a file path: %w", nameOrPath, strings.Join(WeightTables(), ", "), err)
+	}
+	return charInfo, err
+}
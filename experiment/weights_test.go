@@ -0,0 +1,43 @@
+package experiment
+
+import "testing"
+
+func TestWeightTables(t *testing.T) {
+	tests := []struct {
+		name  string
+		chars int
+	}{
+		{"blocks", 5},
+		{"dense", 70},
+		{"standard", 10},
+	}
+	names := WeightTables()
+	if len(names) != len(tests) {
+		t.Fatalf("got tables %v", names)
+	}
+	for i, tt := range tests {
+		if names[i] != tt.name {
+			t.Errorf("table %d: got %q, want %q", i, names[i], tt.name)
+		}
+		charInfo, err := LoadWeights(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(charInfo.CharacterData) != tt.chars {
+			t.Errorf("%s: got %d characters, want %d", tt.name, len(charInfo.CharacterData), tt.chars)
+		}
+		if err := (CharacterScale{Info: charInfo}).Validate(); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+	if _, err := LoadWeights("missing-table"); err == nil {
+		t.Error("expected an error for a missing table")
+	}
+}
+
+func TestCharacterScaleUndrawable(t *testing.T) {
+	err := CharacterScale{Info: CharacterInfoFromRamp("a中")}.Validate()
+	if err == nil {
+		t.Error("expected an error for a character without glyph")
+	}
+}
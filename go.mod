@@ -5,11 +5,12 @@ go 1.17
 require (
 	github.com/urfave/cli/v2 v2.3.0
 	golang.org/x/image v0.0.0-20211028202545-6944b10bf410
-	gopkg.in/go-playground/colors.v1 v1.2.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/cpuguy83/go-md2man/v2 v2.0.0-20190314233015-f79a8a8ca69d // indirect
 	github.com/russross/blackfriday/v2 v2.0.1 // indirect
 	github.com/shurcooL/sanitized_anchor_name v1.0.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
 )
@@ -0,0 +1,221 @@
+package imagefilter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/victorvbello/img-processing/progress"
+)
+
+// Animation is a sequence of frames, it behave as an image.Image showing its
+// first frame so it can go through any Filter, the gif encoder write every frame
+type Animation struct {
+	Frames []image.Image
+	// Delay of each frame in 100ths of a second
+	Delay []int
+	// Disposal method of each frame, gif.DisposalNone, DisposalBackground or
+	// DisposalPrevious, it can be nil
+	Disposal []byte
+	// LoopCount 0 loop forever, -1 show the frames once, n repeat n times
+	LoopCount int
+}
+
+// NewAnimation make an animation whose frames all last delay
+func NewAnimation(frames []image.Image, delay int, loopCount int) *Animation {
+	delays := make([]int, len(frames))
+	for i := range delays {
+		delays[i] = delay
+	}
+	return &Animation{Frames: frames, Delay: delays, LoopCount: loopCount}
+}
+
+func (a *Animation) ColorModel() color.Model {
+	return a.Frames[0].ColorModel()
+}
+
+func (a *Animation) Bounds() image.Rectangle {
+	return a.Frames[0].Bounds()
+}
+
+func (a *Animation) At(x, y int) color.Color {
+	return a.Frames[0].At(x, y)
+}
+
+// SequencePath return the path of the frame index of a sequence, the number
+// is added before the extension: out_0001.png
+func SequencePath(fileName string, index int) string {
+	ext := filepath.Ext(fileName)
+	return fmt.Sprintf("%s_%04d%s", strings.TrimSuffix(fileName, ext), index+1, ext)
+}
+
+// EncodeSequence write every frame of a to its own numbered file next to
+// fileName, it return the written paths. On error the frames already written
+// are removed
+func EncodeSequence(ctx context.Context, a *Animation, fileName string, o Options) ([]string, error) {
+	paths := make([]string, len(a.Frames))
+	for i, frame := range a.Frames {
+		paths[i] = SequencePath(fileName, i)
+		if err := EncodeFile(ctx, frame, paths[i], o); err != nil {
+			for _, p := range paths[:i] {
+				os.Remove(p)
+			}
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+func encodeGIFAnimation(w io.Writer, a *Animation, o Options) error {
+	if len(a.Frames) == 0 {
+		return errors.New("animation without frames")
+	}
+	gifOptions, err := gifOptions(o)
+	if err != nil {
+		return err
+	}
+	g := &gif.GIF{
+		Image:     make([]*image.Paletted, len(a.Frames)),
+		Delay:     make([]int, len(a.Frames)),
+		LoopCount: a.LoopCount,
+	}
+	for i, frame := range a.Frames {
+		bounds := frame.Bounds()
+		p, ok := frame.(*image.Paletted)
+		if !ok {
+			palette := gifOptions.Quantizer.Quantize(make(color.Palette, 0, gifOptions.NumColors), frame)
+			p = image.NewPaletted(bounds, palette)
+			gifOptions.Drawer.Draw(p, bounds, frame, bounds.Min)
+		}
+		g.Image[i] = p
+		if i < len(a.Delay) {
+			g.Delay[i] = a.Delay[i]
+		}
+	}
+	if len(a.Disposal) == len(a.Frames) {
+		g.Disposal = a.Disposal
+	}
+	return gif.EncodeAll(w, g)
+}
+
+// decodeGIF return the first frame of a still gif or an *Animation whose
+// frames are the full canvas after drawing each frame, so filters see the
+// whole picture
+func decodeGIF(r io.Reader) (image.Image, error) {
+	g, err := gif.DecodeAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(g.Image) == 1 {
+		return g.Image[0], nil
+	}
+
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	canvas := image.NewRGBA(bounds)
+	a := &Animation{
+		Frames:    make([]image.Image, len(g.Image)),
+		Delay:     g.Delay,
+		Disposal:  g.Disposal,
+		LoopCount: g.LoopCount,
+	}
+	for i, frame := range g.Image {
+		var previous *image.RGBA
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			draw.Draw(previous, bounds, canvas, bounds.Min, draw.Src)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		full := image.NewRGBA(bounds)
+		draw.Draw(full, bounds, canvas, bounds.Min, draw.Src)
+		a.Frames[i] = full
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return a, nil
+}
+
+// ApplyFrames apply f to every frame of a on Workers(ctx) goroutines, delays,
+// disposal and loop count are kept
+func ApplyFrames(ctx context.Context, f Filter, a *Animation) (*Animation, error) {
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := &Animation{
+		Frames:    make([]image.Image, len(a.Frames)),
+		Delay:     a.Delay,
+		Disposal:  a.Disposal,
+		LoopCount: a.LoopCount,
+	}
+	// the frames are not reported one by one, only the number already done
+	o := progress.FromContext(ctx)
+	frameCtx := progress.WithObserver(ctx, nil)
+	var framesDone int32
+	framesIndex := make(chan int)
+	for w := 0; w < Workers(ctx); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range framesIndex {
+				frame, err := f.Apply(frameCtx, a.Frames[i])
+				if err == nil {
+					if _, ok := frame.(*Animation); ok {
+						err = fmt.Errorf("%T make an animation from an animation frame", f)
+					}
+				}
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("frame %d: %w", i, err)
+						cancel()
+					})
+					continue
+				}
+				result.Frames[i] = frame
+				done := int(atomic.AddInt32(&framesDone, 1))
+				progress.Notify(o, progress.Event{
+					Kind:    progress.PROGRESS,
+					Percent: progress.Percent(done, len(a.Frames)),
+					Message: fmt.Sprintf("frame %d/%d", done, len(a.Frames)),
+				})
+			}
+		}()
+	}
+
+	for i := range a.Frames {
+		if ctx.Err() != nil {
+			break
+		}
+		framesIndex <- i
+	}
+	close(framesIndex)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,135 @@
+package imagefilter
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"reflect"
+	"testing"
+)
+
+var (
+	transparent = color.RGBA{}
+	red         = color.RGBA{0xff, 0, 0, 0xff}
+	green       = color.RGBA{0, 0xff, 0, 0xff}
+	blue        = color.RGBA{0, 0, 0xff, 0xff}
+	testPalette = color.Palette{transparent, red, green, blue}
+)
+
+func gifFrame(r image.Rectangle, c color.Color) *image.Paletted {
+	p := image.NewPaletted(r, testPalette)
+	index := uint8(testPalette.Index(c))
+	for i := range p.Pix {
+		p.Pix[i] = index
+	}
+	return p
+}
+
+// testGIF encode a 4x4 gif: a red frame, a green corner cleared to the
+// background, a blue corner restored to the previous canvas and a green dot
+func testGIF(t *testing.T) []byte {
+	g := &gif.GIF{
+		Image: []*image.Paletted{
+			gifFrame(image.Rect(0, 0, 4, 4), red),
+			gifFrame(image.Rect(0, 0, 2, 2), green),
+			gifFrame(image.Rect(2, 2, 4, 4), blue),
+			gifFrame(image.Rect(1, 1, 2, 2), green),
+		},
+		Delay:     []int{10, 20, 30, 40},
+		Disposal:  []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalPrevious, gif.DisposalNone},
+		LoopCount: 3,
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decodeTestGIF(t *testing.T, b []byte) *Animation {
+	img, _, err := Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, ok := img.(*Animation)
+	if !ok {
+		t.Fatalf("decoded %T, want *Animation", img)
+	}
+	return a
+}
+
+func TestDecodeGIF(t *testing.T) {
+	a := decodeTestGIF(t, testGIF(t))
+	if len(a.Frames) != 4 {
+		t.Fatalf("%d frames, want 4", len(a.Frames))
+	}
+	if !reflect.DeepEqual(a.Delay, []int{10, 20, 30, 40}) {
+		t.Errorf("delay %v", a.Delay)
+	}
+	if !reflect.DeepEqual(a.Disposal, []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalPrevious, gif.DisposalNone}) {
+		t.Errorf("disposal %v", a.Disposal)
+	}
+	if a.LoopCount != 3 {
+		t.Errorf("loop count %d, want 3", a.LoopCount)
+	}
+
+	tests := []struct {
+		frame int
+		x, y  int
+		want  color.RGBA
+	}{
+		{0, 0, 0, red},
+		{0, 3, 3, red},
+		// the partial frame is drawn over the red canvas
+		{1, 0, 0, green},
+		{1, 1, 1, green},
+		{1, 3, 3, red},
+		// frame 1 is cleared to the background before frame 2
+		{2, 0, 0, transparent},
+		{2, 3, 0, red},
+		{2, 3, 3, blue},
+		// frame 2 is replaced by the previous canvas before frame 3
+		{3, 0, 0, transparent},
+		{3, 1, 1, green},
+		{3, 3, 3, red},
+	}
+	for _, tt := range tests {
+		frame := a.Frames[tt.frame]
+		if frame.Bounds() != image.Rect(0, 0, 4, 4) {
+			t.Errorf("frame %d bounds %v", tt.frame, frame.Bounds())
+		}
+		if got := color.RGBAModel.Convert(frame.At(tt.x, tt.y)); got != tt.want {
+			t.Errorf("frame %d at %d,%d: got %v, want %v", tt.frame, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestApplyFrames(t *testing.T) {
+	a := decodeTestGIF(t, testGIF(t))
+	ctx := WithWorkers(context.Background(), 2)
+	result, err := ApplyFrames(ctx, GreyScale{}, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Frames) != len(a.Frames) {
+		t.Fatalf("%d frames, want %d", len(result.Frames), len(a.Frames))
+	}
+	for i, frame := range result.Frames {
+		if frame == nil || frame.Bounds() != a.Frames[i].Bounds() {
+			t.Errorf("frame %d: %v", i, frame)
+		}
+	}
+
+	// the timing survive the encoding of the filtered animation
+	var buf bytes.Buffer
+	if err := Encode(&buf, result, Options{Format: "gif"}); err != nil {
+		t.Fatal(err)
+	}
+	got := decodeTestGIF(t, buf.Bytes())
+	if !reflect.DeepEqual(got.Delay, a.Delay) || !reflect.DeepEqual(got.Disposal, a.Disposal) || got.LoopCount != a.LoopCount {
+		t.Errorf("got delay %v disposal %v loop %d, want %v %v %d",
+			got.Delay, got.Disposal, got.LoopCount, a.Delay, a.Disposal, a.LoopCount)
+	}
+}
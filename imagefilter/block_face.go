@@ -0,0 +1,138 @@
+package imagefilter
+
+import (
+	"image"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// BLOCK_FIRST and BLOCK_LAST are the unicode block elements, ▀ to ▟
+	BLOCK_FIRST = '▀'
+	BLOCK_LAST  = '▟'
+)
+
+// quadrants of a cell, ▖ to ▟ are made of them
+const (
+	upperLeft = 1 << iota
+	upperRight
+	lowerLeft
+	lowerRight
+)
+
+// blockShape is a block element as the part of the cell it fill, from 0 to
+// 8 eighths on each axis, or as the quadrants it fill. alpha is the coverage
+// of the shades
+type blockShape struct {
+	x0, y0, x1, y1 int
+	quadrants      int
+	alpha          uint8
+}
+
+var blockShapes = [BLOCK_LAST - BLOCK_FIRST + 1]blockShape{
+	{0, 0, 8, 4, 0, 0xff},                                   // ▀ upper half
+	{0, 7, 8, 8, 0, 0xff},                                   // ▁ lower one eighth
+	{0, 6, 8, 8, 0, 0xff},                                   // ▂
+	{0, 5, 8, 8, 0, 0xff},                                   // ▃
+	{0, 4, 8, 8, 0, 0xff},                                   // ▄ lower half
+	{0, 3, 8, 8, 0, 0xff},                                   // ▅
+	{0, 2, 8, 8, 0, 0xff},                                   // ▆
+	{0, 1, 8, 8, 0, 0xff},                                   // ▇
+	{0, 0, 8, 8, 0, 0xff},                                   // █ full block
+	{0, 0, 7, 8, 0, 0xff},                                   // ▉ left seven eighths
+	{0, 0, 6, 8, 0, 0xff},                                   // ▊
+	{0, 0, 5, 8, 0, 0xff},                                   // ▋
+	{0, 0, 4, 8, 0, 0xff},                                   // ▌ left half
+	{0, 0, 3, 8, 0, 0xff},                                   // ▍
+	{0, 0, 2, 8, 0, 0xff},                                   // ▎
+	{0, 0, 1, 8, 0, 0xff},                                   // ▏
+	{4, 0, 8, 8, 0, 0xff},                                   // ▐ right half
+	{0, 0, 8, 8, 0, 0x40},                                   // ░ light shade
+	{0, 0, 8, 8, 0, 0x80},                                   // ▒ medium shade
+	{0, 0, 8, 8, 0, 0xc0},                                   // ▓ dark shade
+	{0, 0, 8, 1, 0, 0xff},                                   // ▔ upper one eighth
+	{7, 0, 8, 8, 0, 0xff},                                   // ▕ right one eighth
+	{0, 0, 0, 0, lowerLeft, 0xff},                           // ▖
+	{0, 0, 0, 0, lowerRight, 0xff},                          // ▗
+	{0, 0, 0, 0, upperLeft, 0xff},                           // ▘
+	{0, 0, 0, 0, upperLeft | lowerLeft | lowerRight, 0xff},  // ▙
+	{0, 0, 0, 0, upperLeft | lowerRight, 0xff},              // ▚
+	{0, 0, 0, 0, upperLeft | upperRight | lowerLeft, 0xff},  // ▛
+	{0, 0, 0, 0, upperLeft | upperRight | lowerRight, 0xff}, // ▜
+	{0, 0, 0, 0, upperRight, 0xff},                          // ▝
+	{0, 0, 0, 0, upperRight | lowerLeft, 0xff},              // ▞
+	{0, 0, 0, 0, upperRight | lowerLeft | lowerRight, 0xff}, // ▟
+}
+
+// blockFace draw the block elements missing from a bitmap face by filling
+// the parts of its cell, every other rune is drawn by the face
+type blockFace struct {
+	*basicfont.Face
+	// masks hold the cells of all the block elements one under the other
+	masks *image.Alpha
+}
+
+func newBlockFace(face *basicfont.Face) *blockFace {
+	w, h := face.Advance, face.Ascent+face.Descent
+	masks := image.NewAlpha(image.Rect(0, 0, w, h*len(blockShapes)))
+	for i, s := range blockShapes {
+		cell := image.Rect(0, i*h, w, (i+1)*h)
+		for y := cell.Min.Y; y < cell.Max.Y; y++ {
+			for x := cell.Min.X; x < cell.Max.X; x++ {
+				if s.covers(8*x/w, 8*(y-cell.Min.Y)/h) {
+					masks.Pix[masks.PixOffset(x, y)] = s.alpha
+				}
+			}
+		}
+	}
+	return &blockFace{face, masks}
+}
+
+// covers report whether the eighth x, y of the cell is filled
+func (s blockShape) covers(x, y int) bool {
+	if s.quadrants != 0 {
+		q := upperLeft
+		if x >= 4 {
+			q <<= 1
+		}
+		if y >= 4 {
+			q <<= 2
+		}
+		return s.quadrants&q != 0
+	}
+	return x >= s.x0 && x < s.x1 && y >= s.y0 && y < s.y1
+}
+
+// HasGlyph report whether r is drawn, by the face or as a block element
+func (f *blockFace) HasGlyph(r rune) bool {
+	if r >= BLOCK_FIRST && r <= BLOCK_LAST {
+		return true
+	}
+	for _, rng := range f.Ranges {
+		if r >= rng.Low && r < rng.High {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *blockFace) Glyph(dot fixed.Point26_6, r rune) (dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool) {
+	if r < BLOCK_FIRST || r > BLOCK_LAST {
+		return f.Face.Glyph(dot, r)
+	}
+	h := f.Ascent + f.Descent
+	x, y := (dot.X + 32).Floor(), (dot.Y + 32).Floor()
+	dr = image.Rect(x, y-f.Ascent, x+f.Advance, y+f.Descent)
+	return dr, f.masks, image.Pt(0, int(r-BLOCK_FIRST)*h), fixed.I(f.Advance), true
+}
+
+func (f *blockFace) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.Int26_6, ok bool) {
+	if r < BLOCK_FIRST || r > BLOCK_LAST {
+		return f.Face.GlyphBounds(r)
+	}
+	return fixed.R(0, -f.Ascent, f.Advance, f.Descent), fixed.I(f.Advance), true
+}
+
+var _ font.Face = (*blockFace)(nil)
@@ -0,0 +1,81 @@
+package imagefilter
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/victorvbello/img-processing/ascii"
+)
+
+func TestBlockFaceGlyph(t *testing.T) {
+	w, h := textFace.Advance, textFace.Ascent+textFace.Descent
+	tests := []struct {
+		r rune
+		// alpha of the upper left, upper right, lower left and lower right
+		// pixels of the cell
+		want [4]uint8
+	}{
+		{'█', [4]uint8{0xff, 0xff, 0xff, 0xff}},
+		{'▀', [4]uint8{0xff, 0xff, 0, 0}},
+		{'▄', [4]uint8{0, 0, 0xff, 0xff}},
+		{'▌', [4]uint8{0xff, 0, 0xff, 0}},
+		{'▐', [4]uint8{0, 0xff, 0, 0xff}},
+		{'░', [4]uint8{0x40, 0x40, 0x40, 0x40}},
+		{'▒', [4]uint8{0x80, 0x80, 0x80, 0x80}},
+		{'▓', [4]uint8{0xc0, 0xc0, 0xc0, 0xc0}},
+		{'▚', [4]uint8{0xff, 0, 0, 0xff}},
+		{'▟', [4]uint8{0, 0xff, 0xff, 0xff}},
+		{'▁', [4]uint8{0, 0, 0xff, 0xff}},
+		{'▕', [4]uint8{0, 0xff, 0, 0xff}},
+	}
+	dot := fixed.P(16, 20)
+	for _, tt := range tests {
+		dr, mask, maskp, advance, ok := textFace.Glyph(dot, tt.r)
+		if !ok || advance != fixed.I(w) {
+			t.Fatalf("%c: ok %v advance %v", tt.r, ok, advance)
+		}
+		if want := image.Rect(16, 20-textFace.Ascent, 16+w, 20+textFace.Descent); dr != want {
+			t.Errorf("%c: rect %v, want %v", tt.r, dr, want)
+		}
+		corners := []image.Point{{0, 0}, {w - 1, 0}, {0, h - 1}, {w - 1, h - 1}}
+		for i, p := range corners {
+			_, _, _, a := mask.At(maskp.X+p.X, maskp.Y+p.Y).RGBA()
+			if uint8(a>>8) != tt.want[i] {
+				t.Errorf("%c: corner %v alpha %#x, want %#x", tt.r, p, a>>8, tt.want[i])
+			}
+		}
+	}
+	if !textFace.HasGlyph('A') || !textFace.HasGlyph('▞') || textFace.HasGlyph('中') {
+		t.Error("HasGlyph does not match the face and the block elements")
+	}
+	if _, _, _, _, ok := textFace.Glyph(dot, 'A'); !ok {
+		t.Error("the face glyphs are not drawn")
+	}
+	var _ font.Face = textFace
+}
+
+func TestMakeFromGridBlocks(t *testing.T) {
+	fi := NewFilterImg("", image.NewRGBA(image.Rect(0, 0, 40, 30)), nil, 0, nil)
+	g := &ascii.Grid{Cols: 2, Rows: 1, Cells: []ascii.Cell{{Char: "█"}, {Char: " "}}}
+	img, err := fi.MakeFromGrid(context.Background(), g, TextStyle{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dark int
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if img.At(x, y) == (color.RGBA{0, 0, 0, 0xff}) {
+				dark++
+			}
+		}
+	}
+	if want := textFace.Advance * (textFace.Ascent + textFace.Descent); dark != want {
+		t.Errorf("%d black pixels, want a full %d pixels cell", dark, want)
+	}
+}
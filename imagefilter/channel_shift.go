@@ -0,0 +1,190 @@
+package imagefilter
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+const (
+	MASK_ALL          = "all"
+	MASK_CHECKERBOARD = "checkerboard"
+	MASK_STRIPES      = "stripes"
+	MASK_NOISE        = "noise"
+	CHANNELS          = "rgba"
+)
+
+// ChannelShift subtract Factor from the chosen channels of the pixels of
+// Mask. Channels, Zero and Order are made of the letters r, g, b and a
+type ChannelShift struct {
+	Factor uint8
+	// Channels shifted by Factor
+	Channels string
+	// Zero channels set to 0 before the shift
+	Zero string
+	// Order is the source channel of r, g, b and a, "rgba" or empty keep them
+	Order string
+	// Mask one of all, checkerboard, stripes (horizontal), noise
+	Mask string
+	// Size in pixels of the checkerboard squares and the stripes, 0 is 1
+	Size int
+	// Clamp stop the shift at 0 instead of wrapping around
+	Clamp bool
+	// Seed of the noise mask
+	Seed int64
+}
+
+func (cs ChannelShift) String() string {
+	s := fmt.Sprintf("channel-shift factor=%d channels=%s mask=%s", cs.Factor, cs.Channels, cs.Mask)
+	if cs.Zero != "" {
+		s += " zero=" + cs.Zero
+	}
+	if cs.Order != "" && cs.Order != CHANNELS {
+		s += " order=" + cs.Order
+	}
+	if cs.Size > 1 {
+		s += fmt.Sprintf(" size=%d", cs.Size)
+	}
+	if cs.Mask == MASK_NOISE {
+		s += fmt.Sprintf(" seed=%d", cs.Seed)
+	}
+	if cs.Clamp {
+		s += " clamp"
+	}
+	return s
+}
+
+// channelSet return which of r, g, b and a are in s
+func channelSet(name, s string) ([4]bool, error) {
+	var set [4]bool
+	for _, c := range strings.ToLower(s) {
+		i := strings.IndexRune(CHANNELS, c)
+		if i < 0 {
+			return set, fmt.Errorf("channel-shift %s: channel %q not available, use r, g, b, a", name, c)
+		}
+		set[i] = true
+	}
+	return set, nil
+}
+
+// Validate check the channels, the order and the mask
+func (cs ChannelShift) Validate() error {
+	if _, err := cs.sources(); err != nil {
+		return err
+	}
+	if _, err := channelSet("channels", cs.Channels); err != nil {
+		return err
+	}
+	if _, err := channelSet("zero", cs.Zero); err != nil {
+		return err
+	}
+	if _, err := cs.mask(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cs ChannelShift) sources() ([4]int, error) {
+	src := [4]int{0, 1, 2, 3}
+	if cs.Order == "" {
+		return src, nil
+	}
+	if len(cs.Order) != 4 {
+		return src, fmt.Errorf("channel-shift order %q must have 4 channels", cs.Order)
+	}
+	for i, c := range strings.ToLower(cs.Order) {
+		src[i] = strings.IndexRune(CHANNELS, c)
+		if src[i] < 0 {
+			return src, fmt.Errorf("channel-shift order: channel %q not available, use r, g, b, a", c)
+		}
+	}
+	return src, nil
+}
+
+// mask return the function reporting whether the pixel x, y is changed
+func (cs ChannelShift) mask() (func(x, y int) bool, error) {
+	size := cs.Size
+	if size < 1 {
+		size = 1
+	}
+	switch cs.Mask {
+	case "", MASK_ALL:
+		return func(x, y int) bool { return true }, nil
+	case MASK_CHECKERBOARD:
+		return func(x, y int) bool { return (x/size+y/size)%2 == 0 }, nil
+	case MASK_STRIPES:
+		return func(x, y int) bool { return (y/size)%2 == 0 }, nil
+	case MASK_NOISE:
+		seed := uint64(cs.Seed)
+		return func(x, y int) bool { return noiseBit(seed, x, y) }, nil
+	}
+	return nil, fmt.Errorf("channel-shift mask %q not available, use all, checkerboard, stripes, noise", cs.Mask)
+}
+
+// noiseBit hash the pixel position with seed, the result does not depend on
+// the order the pixels are visited so the bands can run in parallel
+func noiseBit(seed uint64, x, y int) bool {
+	h := seed ^ uint64(x)*0x9e3779b97f4a7c15 ^ uint64(y)*0xc2b2ae3d27d4eb4f
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	return h&1 == 0
+}
+
+func (cs ChannelShift) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	fi := newFilterImgFrom(ctx, "", img, cs.Factor)
+	if _, err := fi.ChannelShift(0, cs); err != nil {
+		return nil, err
+	}
+	return fi.RGBA(), nil
+}
+
+// ChannelShift apply cs to the pixels, the Factor of cs is used instead of
+// fi.Factor
+func (fi *FilterImg) ChannelShift(id int, cs ChannelShift) (image.Image, error) {
+	if err := cs.Validate(); err != nil {
+		return nil, err
+	}
+	src, _ := cs.sources()
+	shift, _ := channelSet("channels", cs.Channels)
+	zero, _ := channelSet("zero", cs.Zero)
+	inMask, _ := cs.mask()
+	f := cs.Factor
+
+	dst := fi.buffer()
+	fi.rangeParallel(func(x, y int, c color.RGBA) {
+		if !inMask(x, y) {
+			return
+		}
+		in := [4]uint8{c.R, c.G, c.B, c.A}
+		var out [4]uint8
+		for i := range out {
+			v := in[src[i]]
+			if zero[i] {
+				v = 0
+			}
+			if shift[i] {
+				if cs.Clamp && v < f {
+					v = 0
+				} else {
+					v -= f
+				}
+			}
+			out[i] = v
+		}
+		dst.SetRGBA(x, y, color.RGBA{out[0], out[1], out[2], out[3]})
+	})
+	return fi, nil
+}
+
+// the presets of the random color filters, every channel is shifted with
+// wrap around
+func randomColorShift(f uint8) ChannelShift {
+	return ChannelShift{Factor: f, Channels: CHANNELS, Order: "abga", Mask: MASK_ALL}
+}
+
+func zeroChannelShift(zero string, f uint8) ChannelShift {
+	return ChannelShift{Factor: f, Channels: CHANNELS, Zero: zero, Mask: MASK_CHECKERBOARD}
+}
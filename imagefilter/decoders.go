@@ -2,77 +2,77 @@ package imagefilter
 
 import (
 	"bufio"
-	"errors"
 	"image"
 	"image/jpeg"
 	"image/png"
-	"net/http"
+	"io"
 	"os"
 )
 
-func decodeJPEG(imgFile *os.File) (image.Image, error) {
-	img, err := jpeg.Decode(imgFile)
+func decodeJPEG(r io.Reader) (image.Image, error) {
+	img, err := jpeg.Decode(r)
 	if err != nil {
 		return nil, err
 	}
 	return img, nil
 }
 
-func decodePNG(imgFile *os.File) (image.Image, error) {
-	img, err := png.Decode(imgFile)
+func decodePNG(r io.Reader) (image.Image, error) {
+	img, err := png.Decode(r)
 	if err != nil {
 		return nil, err
 	}
 	return img, nil
 }
 
-// DecodeImg open file and decode image using content type
-func DecodeImg(imgFilepath string) (image.Image, error) {
-	var finalImg image.Image
-	imgFile, err := os.Open(imgFilepath)
-	if err != nil {
-		return nil, err
-	}
-	defer imgFile.Close()
+// Decode sniff the content type of r and decode the image with the first
+// registered decoder that match, it also return the format name. Errors are a
+// *FileError of Kind ErrDecode
+func Decode(r io.Reader) (image.Image, string, error) {
+	img, name, err := decode(r)
+	return img, name, fileError(ErrDecode, "", err)
+}
 
-	buff := bufio.NewReader(imgFile)
+func decode(r io.Reader) (image.Image, string, error) {
+	buff := bufio.NewReader(r)
 	buffType, err := buff.Peek(512)
-	if err != nil {
-		return nil, err
+	if err != nil && !(err == io.EOF && len(buffType) > 0) {
+		return nil, "", err
 	}
 
-	imgFile.Seek(0, 0)
-	contentType := http.DetectContentType(buffType)
-	switch contentType {
-	case "image/jpeg":
-		finalImg, err = decodeJPEG(imgFile)
-	case "image/png":
-		finalImg, err = decodePNG(imgFile)
-	default:
-		finalImg = nil
-		err = errors.New("content type not available")
+	d, ok := sniffDecoder(buffType)
+	if !ok {
+		return nil, "", errContentType
 	}
+	img, err := d.Decode(buff)
+	return img, d.Name, err
+}
 
-	return finalImg, err
-
+// DecodeImg open file and decode image using content type
+func DecodeImg(imgFilepath string) (image.Image, error) {
+	return decodeByPath(imgFilepath, func(r io.Reader) (image.Image, error) {
+		img, _, err := decode(r)
+		return img, err
+	})
 }
 
 func DecodeJPEGByPath(imgFilepath string) (image.Image, error) {
-	imgFile, err := os.Open(imgFilepath)
-	if err != nil {
-		return nil, err
-	}
-	defer imgFile.Close()
-
-	return decodeJPEG(imgFile)
+	return decodeByPath(imgFilepath, decodeJPEG)
 }
 
 func DecodePNGByPath(imgFilepath string) (image.Image, error) {
+	return decodeByPath(imgFilepath, decodePNG)
+}
+
+// decodeByPath open imgFilepath and decode it with fn, errors are a
+// *FileError of Kind ErrDecode with the path
+func decodeByPath(imgFilepath string, fn func(io.Reader) (image.Image, error)) (image.Image, error) {
 	imgFile, err := os.Open(imgFilepath)
 	if err != nil {
-		return nil, err
+		return nil, fileError(ErrDecode, imgFilepath, err)
 	}
 	defer imgFile.Close()
 
-	return decodePNG(imgFile)
+	img, err := fn(imgFile)
+	return img, fileError(ErrDecode, imgFilepath, err)
 }
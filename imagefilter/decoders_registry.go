@@ -0,0 +1,82 @@
+package imagefilter
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"net/http"
+	"sync"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+	"golang.org/x/image/webp"
+)
+
+// Decoder decode the images whose first bytes match Sniff
+type Decoder struct {
+	Name   string
+	Sniff  func(head []byte) bool
+	Decode func(r io.Reader) (image.Image, error)
+}
+
+var (
+	decodersMu sync.RWMutex
+	decoders   []Decoder
+)
+
+// RegisterDecoder add a format to Decode, decoders are tried in register order
+func RegisterDecoder(d Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders = append(decoders, d)
+}
+
+// DecoderNames return the names of the registered formats
+func DecoderNames() []string {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	names := make([]string, len(decoders))
+	for i, d := range decoders {
+		names[i] = d.Name
+	}
+	return names
+}
+
+func sniffDecoder(head []byte) (Decoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	for _, d := range decoders {
+		if d.Sniff(head) {
+			return d, true
+		}
+	}
+	return Decoder{}, false
+}
+
+// SniffContentType match head when http.DetectContentType return contentType
+func SniffContentType(contentType string) func(head []byte) bool {
+	return func(head []byte) bool {
+		return http.DetectContentType(head) == contentType
+	}
+}
+
+// SniffMagic match head when it start with any of the magic strings
+func SniffMagic(magic ...string) func(head []byte) bool {
+	return func(head []byte) bool {
+		for _, m := range magic {
+			if bytes.HasPrefix(head, []byte(m)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func init() {
+	RegisterDecoder(Decoder{"jpeg", SniffContentType("image/jpeg"), decodeJPEG})
+	RegisterDecoder(Decoder{"png", SniffContentType("image/png"), decodePNG})
+	RegisterDecoder(Decoder{"gif", SniffContentType("image/gif"), decodeGIF})
+	RegisterDecoder(Decoder{"bmp", SniffContentType("image/bmp"), bmp.Decode})
+	RegisterDecoder(Decoder{"webp", SniffContentType("image/webp"), webp.Decode})
+	RegisterDecoder(Decoder{"tiff", SniffMagic("II*\x00", "MM\x00*"), tiff.Decode})
+}
@@ -1,18 +1,136 @@
 package imagefilter
 
 import (
-	"errors"
+	"context"
+	"fmt"
 	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
 	"image/jpeg"
 	"image/png"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+
+	"github.com/victorvbello/img-processing/progress"
 )
 
-func EncodeIMG(img image.Image, fileName string) (*os.File, error) {
-	var f *os.File
-	var err error
+// Options of Encode, Format is the format name or extension (jpeg, jpg, png,
+// gif, bmp, tif, tiff), the zero value of every other field use the
+// default of its encoder
+type Options struct {
+	Format string
+	// JPEGQuality from 1 to 100
+	JPEGQuality int
+	// PNGCompression one of default, none, speed, best
+	PNGCompression string
+	// GIFPalette one of plan9, websafe, adaptive
+	GIFPalette string
+	// GIFDither apply Floyd-Steinberg error diffusion when drawing on the palette
+	GIFDither bool
+	// TIFFCompression one of none, deflate
+	TIFFCompression string
+	// Metadata text written in the png, jpeg and gif outputs
+	Metadata map[string]string
+}
+
+// FormatFromPath return the format name of the extension of fileName
+func FormatFromPath(fileName string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))
+}
+
+func normalizeFormat(format string) string {
+	format = strings.ToLower(strings.TrimPrefix(format, "."))
+	switch format {
+	case "jpg":
+		return "jpeg"
+	case "tif":
+		return "tiff"
+	}
+	return format
+}
+
+// AnimatedFormat report whether format keep every frame of an *Animation
+func AnimatedFormat(format string) bool {
+	return normalizeFormat(format) == "gif"
+}
+
+// EncodableFormat report whether format, a name or an extension, can be
+// written, webp for instance is only decoded
+func EncodableFormat(format string) bool {
+	return checkFormat(format) == nil
+}
+
+func checkFormat(format string) error {
+	switch normalizeFormat(format) {
+	case "jpeg", "png", "gif", "bmp", "tiff":
+		return nil
+	}
+	return fmt.Errorf("%w %q", ErrUnsupportedFormat, format)
+}
+
+// Encode write img to w using the format of o, errors are a *FileError of
+// Kind ErrEncode
+func Encode(w io.Writer, img image.Image, o Options) error {
+	return fileError(ErrEncode, "", encode(w, img, o))
+}
+
+func encode(w io.Writer, img image.Image, o Options) error {
+	if len(o.Metadata) > 0 {
+		return encodeWithMetadata(w, img, o)
+	}
+	return encodeFormat(w, img, o)
+}
+
+func encodeFormat(w io.Writer, img image.Image, o Options) error {
+	switch normalizeFormat(o.Format) {
+	case "jpeg":
+		return encodeJPEG(w, img, o)
+	case "png":
+		return encodePNG(w, img, o)
+	case "gif":
+		return encodeGIF(w, img, o)
+	case "bmp":
+		return bmp.Encode(w, img)
+	case "tiff":
+		return encodeTIFF(w, img, o)
+	}
+	return errContentType
+}
+
+// ctxWriter fail the writes once ctx is done, so a running encoder stop
+type ctxWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw ctxWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
+
+func encodeFile(ctx context.Context, img image.Image, fileName string, o Options) (*os.File, error) {
+	outFile, err := createEncoded(ctx, img, fileName, o)
+	return outFile, fileError(ErrEncode, fileName, err)
+}
+
+func createEncoded(ctx context.Context, img image.Image, fileName string, o Options) (*os.File, error) {
+	if o.Format == "" {
+		o.Format = FormatFromPath(fileName)
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	for _, w := range encodeWarnings(img, o) {
+		progress.Notify(progress.FromContext(ctx), progress.Event{Kind: progress.WARNING, Stage: fileName, Message: w})
+	}
 
 	dir := filepath.Dir(fileName)
 
@@ -23,40 +141,151 @@ func EncodeIMG(img image.Image, fileName string) (*os.File, error) {
 		}
 	}
 
-	switch strings.TrimPrefix(filepath.Ext(fileName), ".") {
-	case "jpeg", "jpg":
-		f, err = encodeJPEG(img, fileName)
-	case "png":
-		f, err = encodePNG(img, fileName)
-	default:
-		f = nil
-		err = errors.New("content type not available")
+	outFile, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if err := encode(ctxWriter{ctx, outFile}, img, o); err != nil {
+		outFile.Close()
+		os.Remove(fileName)
+		return nil, err
 	}
-	return f, err
+	return outFile, outFile.Close()
 }
 
-func encodeJPEG(img image.Image, fileName string) (*os.File, error) {
-	outFile, err := os.Create(fileName)
+// encodeWarnings return what the format of o lose from img: the alpha
+// channel of jpeg and the metadata of bmp and tiff
+func encodeWarnings(img image.Image, o Options) []string {
+	var warnings []string
+	format := normalizeFormat(o.Format)
+	if format == "jpeg" {
+		if op, ok := img.(interface{ Opaque() bool }); ok && !op.Opaque() {
+			warnings = append(warnings, "jpeg has no alpha channel, the transparent pixels are written opaque")
+		}
+	}
+	if len(o.Metadata) > 0 && (format == "bmp" || format == "tiff") {
+		warnings = append(warnings, fmt.Sprintf("%s does not keep the metadata, the seed and the filters are not written", format))
+	}
+	return warnings
+}
+
+// EncodeFile create fileName and its directory and encode img, an empty
+// o.Format use the extension of fileName. The file is removed when the
+// encoding fail or ctx is done before the end, errors are a *FileError of
+// Kind ErrEncode
+func EncodeFile(ctx context.Context, img image.Image, fileName string, o Options) error {
+	_, err := encodeFile(ctx, img, fileName, o)
+	return err
+}
+
+// EncodeIMG create fileName and encode img using the format of its
+// extension, the returned file is already closed
+func EncodeIMG(img image.Image, fileName string) (*os.File, error) {
+	return encodeFile(context.Background(), img, fileName, Options{})
+}
+
+// Validate check the format and the option values before any write
+func (o Options) Validate() error {
+	if err := checkFormat(o.Format); err != nil {
+		return err
+	}
+	if _, err := jpegOptions(o); err != nil {
+		return err
+	}
+	if _, err := pngCompression(o); err != nil {
+		return err
+	}
+	if _, err := gifOptions(o); err != nil {
+		return err
+	}
+	_, err := tiffOptions(o)
+	return err
+}
+
+func jpegOptions(o Options) (*jpeg.Options, error) {
+	if o.JPEGQuality == 0 {
+		return nil, nil
+	}
+	if o.JPEGQuality < 1 || o.JPEGQuality > 100 {
+		return nil, fmt.Errorf("jpeg quality %d out of range 1-100", o.JPEGQuality)
+	}
+	return &jpeg.Options{Quality: o.JPEGQuality}, nil
+}
+
+func encodeJPEG(w io.Writer, img image.Image, o Options) error {
+	jpegOptions, err := jpegOptions(o)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer outFile.Close()
+	return jpeg.Encode(w, img, jpegOptions)
+}
 
-	if err := jpeg.Encode(outFile, img, nil); err != nil {
-		return nil, err
+func pngCompression(o Options) (png.CompressionLevel, error) {
+	switch o.PNGCompression {
+	case "", "default":
+		return png.DefaultCompression, nil
+	case "none":
+		return png.NoCompression, nil
+	case "speed":
+		return png.BestSpeed, nil
+	case "best":
+		return png.BestCompression, nil
 	}
-	return outFile, nil
+	return 0, fmt.Errorf("png compression %s not available", o.PNGCompression)
 }
 
-func encodePNG(img image.Image, fileName string) (*os.File, error) {
-	outFile, err := os.Create(fileName)
+func encodePNG(w io.Writer, img image.Image, o Options) error {
+	level, err := pngCompression(o)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer outFile.Close()
+	e := png.Encoder{CompressionLevel: level}
+	return e.Encode(w, img)
+}
 
-	if err := png.Encode(outFile, img); err != nil {
-		return nil, err
+func gifOptions(o Options) (*gif.Options, error) {
+	gifOptions := &gif.Options{NumColors: 256, Drawer: draw.Src}
+	if o.GIFDither {
+		gifOptions.Drawer = draw.FloydSteinberg
+	}
+	switch o.GIFPalette {
+	case "", "plan9":
+		gifOptions.Quantizer = paletteQuantizer(palette.Plan9)
+	case "websafe":
+		gifOptions.Quantizer = paletteQuantizer(palette.WebSafe)
+	case "adaptive":
+		gifOptions.Quantizer = MedianCutQuantizer{}
+	default:
+		return nil, fmt.Errorf("gif palette %s not available", o.GIFPalette)
+	}
+	return gifOptions, nil
+}
+
+func encodeGIF(w io.Writer, img image.Image, o Options) error {
+	if a, ok := img.(*Animation); ok {
+		return encodeGIFAnimation(w, a, o)
+	}
+	gifOptions, err := gifOptions(o)
+	if err != nil {
+		return err
+	}
+	return gif.Encode(w, img, gifOptions)
+}
+
+func tiffOptions(o Options) (*tiff.Options, error) {
+	switch o.TIFFCompression {
+	case "", "none":
+		return &tiff.Options{Compression: tiff.Uncompressed}, nil
+	case "deflate":
+		return &tiff.Options{Compression: tiff.Deflate, Predictor: true}, nil
+	}
+	return nil, fmt.Errorf("tiff compression %s not available", o.TIFFCompression)
+}
+
+func encodeTIFF(w io.Writer, img image.Image, o Options) error {
+	tiffOptions, err := tiffOptions(o)
+	if err != nil {
+		return err
 	}
-	return outFile, nil
+	return tiff.Encode(w, img, tiffOptions)
 }
@@ -0,0 +1,41 @@
+package imagefilter
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeWarnings(t *testing.T) {
+	opaque := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for i := 3; i < len(opaque.Pix); i += 4 {
+		opaque.Pix[i] = 0xff
+	}
+	transparent := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	transparent.SetRGBA(0, 0, color.RGBA{255, 0, 0, 255})
+	meta := map[string]string{"Seed": "1"}
+	tests := []struct {
+		name string
+		img  image.Image
+		o    Options
+		want int
+	}{
+		{"opaque jpeg", opaque, Options{Format: "jpg"}, 0},
+		{"transparent jpeg", transparent, Options{Format: "jpeg"}, 1},
+		{"transparent png", transparent, Options{Format: "png", Metadata: meta}, 0},
+		{"bmp metadata", opaque, Options{Format: "bmp", Metadata: meta}, 1},
+		{"tiff metadata", opaque, Options{Format: "tif", Metadata: meta}, 1},
+		{"tiff without metadata", opaque, Options{Format: "tiff"}, 0},
+		{"transparent jpeg with metadata", transparent, Options{Format: "jpeg", Metadata: meta}, 1},
+	}
+	for _, tt := range tests {
+		got := encodeWarnings(tt.img, tt.o)
+		if len(got) != tt.want {
+			t.Errorf("%s: got warnings %v, want %d", tt.name, got, tt.want)
+		}
+	}
+	if got := encodeWarnings(transparent, Options{Format: "bmp", Metadata: meta}); !reflect.DeepEqual(got, []string{"bmp does not keep the metadata, the seed and the filters are not written"}) {
+		t.Errorf("got %v", got)
+	}
+}
@@ -0,0 +1,48 @@
+package imagefilter
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnsupportedFormat is returned when no decoder match the content or
+	// the output format has no encoder
+	ErrUnsupportedFormat = errors.New("unsupported format")
+	// ErrDecode is the Kind of the FileError returned by the decoders
+	ErrDecode = errors.New("decode")
+	// ErrEncode is the Kind of the FileError returned by the encoders
+	ErrEncode = errors.New("encode")
+)
+
+var errContentType = fmt.Errorf("%w: content type not available", ErrUnsupportedFormat)
+
+// FileError add the path of the file being decoded or encoded to Err,
+// errors.Is match both Kind and the errors wrapped by Err
+type FileError struct {
+	Kind error
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+	}
+	return fmt.Sprintf("%v %s: %v", e.Kind, e.Path, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+func (e *FileError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func fileError(kind error, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &FileError{kind, path, err}
+}
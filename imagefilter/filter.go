@@ -0,0 +1,162 @@
+package imagefilter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"strconv"
+
+	"github.com/victorvbello/img-processing/dither"
+	"github.com/victorvbello/img-processing/pixelextract"
+	"github.com/victorvbello/img-processing/progress"
+	"github.com/victorvbello/img-processing/threshold"
+)
+
+// Filter is a single image processing step
+type Filter interface {
+	Apply(ctx context.Context, img image.Image) (image.Image, error)
+}
+
+// FilterFunc adapts a plain function to the Filter interface
+type FilterFunc func(ctx context.Context, img image.Image) (image.Image, error)
+
+func (f FilterFunc) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	return f(ctx, img)
+}
+
+// newFilterImgFrom extract the pixels of img, filters return the dense
+// buffer so encoders take their *image.RGBA fast path
+func newFilterImgFrom(ctx context.Context, alias string, img image.Image, f uint8) *FilterImg {
+	fi := NewFilterImg(alias, img, pixelextract.Extract(img), f, progress.FromContext(ctx))
+	fi.Workers = Workers(ctx)
+	return fi
+}
+
+type GreyScale struct{}
+
+func (GreyScale) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	fi := newFilterImgFrom(ctx, "", img, 0)
+	fi.GreyScale(0)
+	return fi.RGBA(), nil
+}
+
+type RandomColor struct {
+	Factor uint8
+}
+
+func (rc RandomColor) String() string {
+	return fmt.Sprintf("random-color factor=%d", rc.Factor)
+}
+
+func (rc RandomColor) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	fi := newFilterImgFrom(ctx, "", img, rc.Factor)
+	fi.RandomColor(0)
+	return fi.RGBA(), nil
+}
+
+type RandomRed struct {
+	Factor uint8
+}
+
+func (rr RandomRed) String() string {
+	return fmt.Sprintf("random-color-red factor=%d", rr.Factor)
+}
+
+func (rr RandomRed) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	fi := newFilterImgFrom(ctx, "", img, rr.Factor)
+	fi.RandomRed(0)
+	return fi.RGBA(), nil
+}
+
+type RandomGreen struct {
+	Factor uint8
+}
+
+func (rg RandomGreen) String() string {
+	return fmt.Sprintf("random-color-green factor=%d", rg.Factor)
+}
+
+func (rg RandomGreen) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	fi := newFilterImgFrom(ctx, "", img, rg.Factor)
+	fi.RandomGreen(0)
+	return fi.RGBA(), nil
+}
+
+type RandomBlue struct {
+	Factor uint8
+}
+
+func (rb RandomBlue) String() string {
+	return fmt.Sprintf("random-color-blue factor=%d", rb.Factor)
+}
+
+func (rb RandomBlue) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	fi := newFilterImgFrom(ctx, "", img, rb.Factor)
+	fi.RandomBlue(0)
+	return fi.RGBA(), nil
+}
+
+// ByteScale resize the image by Scale percent and draw it as "0"/"1" text,
+// the output keeps the canvas size of the original image. Dither choose the
+// dither.Methods used to pick the "1", or a Threshold with a Method, by
+// default the light pixels
+type ByteScale struct {
+	Alias     string
+	Scale     int
+	Dither    string
+	Threshold threshold.Options
+	TextStyle
+}
+
+func (bs ByteScale) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	resizeImg, err := resize(ctx, img, bs.Scale)
+	if err != nil {
+		return nil, err
+	}
+	fi := NewFilterImg(bs.Alias, img, pixelextract.Extract(resizeImg), 0, progress.FromContext(ctx))
+	g, err := fi.ByteScaleGrid(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	var bits *image.Paletted
+	switch {
+	case bs.Dither != "" && bs.Threshold.Method != "":
+		return nil, errors.New("byte-scale: dither and threshold can not be used together")
+	case bs.Dither != "":
+		bits, err = dither.Gray(ctx, resizeImg, bs.Dither, 2)
+	case bs.Threshold.Method != "":
+		bits, err = threshold.Binarize(ctx, resizeImg, bs.Threshold)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bits != nil {
+		// the cells keep the color of the pixels
+		for i := range g.Cells {
+			g.Cells[i].Char = strconv.Itoa(int(bits.Pix[i]))
+		}
+	}
+	return fi.MakeFromGrid(ctx, g, bs.TextStyle)
+}
+
+// Dither reduce the image to Levels grays with one of dither.Methods, the
+// result is paletted so 2 levels are written as a 1-bit png
+type Dither struct {
+	Method string
+	Levels int
+}
+
+func (d Dither) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	return dither.Gray(ctx, img, d.Method, d.Levels)
+}
+
+// Threshold make the pixels black or white, the result is written as a 1-bit
+// png
+type Threshold struct {
+	threshold.Options
+}
+
+func (t Threshold) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	return threshold.Binarize(ctx, img, t.Options)
+}
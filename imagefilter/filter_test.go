@@ -0,0 +1,53 @@
+package imagefilter
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/victorvbello/img-processing/dither"
+	"github.com/victorvbello/img-processing/progress"
+	"github.com/victorvbello/img-processing/threshold"
+)
+
+// TestFiltersProgress check the color filters report their rows, the
+// percent never go back and end at 100
+func TestFiltersProgress(t *testing.T) {
+	src := testImage(60, 200)
+	filters := map[string]Filter{
+		"grayscale":     GreyScale{},
+		"channel-shift": ChannelShift{Factor: 90, Channels: "rgb", Mask: MASK_CHECKERBOARD},
+		"byte":          ByteScale{Scale: 50},
+		"dither":        Dither{Method: dither.ATKINSON, Levels: 2},
+		"dither bayer":  Dither{Method: dither.BAYER_4, Levels: 2},
+		"threshold":     Threshold{threshold.Options{Method: threshold.GAUSSIAN, Window: 5}},
+	}
+	for name, f := range filters {
+		var mu sync.Mutex
+		var percents []float64
+		o := progress.ObserverFunc(func(e progress.Event) {
+			if e.Kind != progress.PROGRESS {
+				return
+			}
+			mu.Lock()
+			percents = append(percents, e.Percent)
+			mu.Unlock()
+		})
+		ctx := WithWorkers(progress.WithObserver(context.Background(), o), 4)
+		if _, err := f.Apply(ctx, src); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(percents) < 2 {
+			t.Errorf("%s: %d progress events", name, len(percents))
+			continue
+		}
+		for i := 1; i < len(percents); i++ {
+			if percents[i] <= percents[i-1] {
+				t.Errorf("%s: percent went from %v to %v", name, percents[i-1], percents[i])
+			}
+		}
+		if last := percents[len(percents)-1]; last != 100 {
+			t.Errorf("%s: last percent %v, want 100", name, last)
+		}
+	}
+}
@@ -1,219 +1,259 @@
 package imagefilter
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
-	"log"
-	"os"
-	"path/filepath"
 	"time"
 
+	"github.com/victorvbello/img-processing/ascii"
 	"github.com/victorvbello/img-processing/pixelextract"
+	"github.com/victorvbello/img-processing/progress"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/inconsolata"
 	"golang.org/x/image/math/fixed"
 )
 
+// FilterImg read the pixels of the embedded image and write the modified ones
+// to a dense RGBA buffer, the buffer is a copy of the image made on the first
+// Set (copy-on-write) unless the FilterImg is made in place
 type FilterImg struct {
 	alias string
 	image.Image
-	custom map[image.Point]color.Color
-	xp     []pixelextract.PixelColor
+	dst    *image.RGBA
+	px     *pixelextract.Pixels
 	Factor uint8
-	log    chan<- string
+	// Workers is the number of row bands processed at the same time by the
+	// color filters, 0 use GOMAXPROCS
+	Workers  int
+	observer progress.Observer
 }
 
-func changeColorRgba(r, g, b, a, f uint8) color.RGBA {
-	var safeR uint8 = 0
-	var safeG uint8 = 0
-	var safeB uint8 = 0
-	var safeA uint8 = 0
-	if r == 0 {
-		safeR = 255
-	}
-	if g == 0 {
-		safeG = 255
-	}
-	if b == 0 {
-		safeB = 255
-	}
-	if newR := r - f; newR > 0 {
-		safeR = newR
-	}
-	if newG := g - f; newG > 0 {
-		safeG = newG
-	}
-	if newB := b - f; newB > 0 {
-		safeB = newB
-	}
-	if newA := a - f; newA > 0 {
-		safeA = newA
-	}
-	return color.RGBA{safeR, safeG, safeB, safeA}
+// NewFilterImg never modify img, its pixels are copied on the first Set.
+// The filters read px, a nil px is extracted from img when needed
+func NewFilterImg(a string, img image.Image, px *pixelextract.Pixels, f uint8, o progress.Observer) *FilterImg {
+	return &FilterImg{alias: a, Image: img, px: px, Factor: f, observer: o}
+}
+
+// NewFilterImgInPlace write every Set directly to img without a copy
+func NewFilterImgInPlace(a string, img *image.RGBA, px *pixelextract.Pixels, f uint8, o progress.Observer) *FilterImg {
+	return &FilterImg{alias: a, Image: img, dst: img, px: px, Factor: f, observer: o}
 }
 
-func NewFilterImg(a string, img image.Image, xp []pixelextract.PixelColor, f uint8, log chan<- string) *FilterImg {
-	return &FilterImg{a, img, map[image.Point]color.Color{}, xp, f, log}
+func (fi *FilterImg) buffer() *image.RGBA {
+	if fi.dst == nil {
+		bounds := fi.Image.Bounds()
+		fi.dst = image.NewRGBA(bounds)
+		draw.Draw(fi.dst, bounds, fi.Image, bounds.Min, draw.Src)
+	}
+	return fi.dst
 }
 
 func (fi *FilterImg) Set(x, y int, c color.Color) {
-	fi.custom[image.Point{x, y}] = c
+	if rgba, ok := c.(color.RGBA); ok {
+		fi.buffer().SetRGBA(x, y, rgba)
+		return
+	}
+	fi.buffer().Set(x, y, c)
+}
+
+func (fi *FilterImg) SetRGBA(x, y int, c color.RGBA) {
+	fi.buffer().SetRGBA(x, y, c)
 }
 
 func (fi *FilterImg) At(x, y int) color.Color {
-	if c := fi.custom[image.Point{x, y}]; c != nil {
-		return c
+	if fi.dst != nil {
+		return fi.dst.RGBAAt(x, y)
 	}
 	return fi.Image.At(x, y)
 }
 
+func (fi *FilterImg) ColorModel() color.Model {
+	if fi.dst != nil {
+		return color.RGBAModel
+	}
+	return fi.Image.ColorModel()
+}
+
+// RGBA return the dense buffer with every modified pixel, the image is
+// copied to it when nothing was set yet
+func (fi *FilterImg) RGBA() *image.RGBA {
+	return fi.buffer()
+}
+
 func (fi *FilterImg) GetAlias() string {
 	return fi.alias
 }
 
-func (fi *FilterImg) GetXp() []pixelextract.PixelColor {
-	return fi.xp
+func (fi *FilterImg) GetPixels() *pixelextract.Pixels {
+	if fi.px == nil {
+		fi.px = pixelextract.Extract(fi.Image)
+	}
+	return fi.px
 }
 
-func (fi *FilterImg) SetXp(nXp []pixelextract.PixelColor) {
-	fi.xp = nXp
+func (fi *FilterImg) SetPixels(px *pixelextract.Pixels) {
+	fi.px = px
 }
 
+// GetXp make the PixelColor of every pixel, GetPixels avoid the allocation
+func (fi *FilterImg) GetXp() []pixelextract.PixelColor {
+	return fi.GetPixels().PixelColors()
+}
+
+// SetImg replace the image and drop the pixels set on the previous one
 func (fi *FilterImg) SetImg(nImg image.Image) {
 	fi.Image = nImg
+	fi.dst = nil
 }
 
+// AddLog send l as a LOG event to the observer, it never block
 func (fi *FilterImg) AddLog(l string) {
-	fi.log <- l
+	progress.Notify(fi.observer, progress.Event{Kind: progress.LOG, Message: l})
 }
 
-func (fi *FilterImg) RandomColor(id int) image.Image {
-	for _, p := range fi.xp {
-		original, ok := color.RGBAModel.Convert(p.OriginalColor).(color.RGBA)
-		if ok {
-			fi.Set(p.X, p.Y, changeColorRgba(original.A, original.B, original.G, original.A, fi.Factor))
+// Notify send e to the observer
+func (fi *FilterImg) Notify(e progress.Event) {
+	progress.Notify(fi.observer, e)
+}
+
+// rangeParallel call fn for every pixel, the rows are split in bands
+// processed on fi.Workers goroutines and reported to the observer
+func (fi *FilterImg) rangeParallel(fn func(x, y int, c color.RGBA)) {
+	px := fi.GetPixels()
+	rows := progress.NewRows(fi.observer, px.Rect.Dy())
+	parallelRows(px.Rect, fi.Workers, func(band image.Rectangle) {
+		for y := band.Min.Y; y < band.Max.Y; y++ {
+			px.RangeRect(image.Rect(band.Min.X, y, band.Max.X, y+1), func(x, y int, c color.RGBA) bool {
+				fn(x, y, c)
+				return true
+			})
+			rows.Done(1)
 		}
-	}
+	})
+}
 
+// RandomColor shift every channel by fi.Factor taking r, g, b from a, b, g
+func (fi *FilterImg) RandomColor(id int) image.Image {
+	fi.ChannelShift(id, randomColorShift(fi.Factor))
 	return fi
 }
 
+// RandomRed zero the red channel and shift every channel by fi.Factor on a
+// checkerboard
 func (fi *FilterImg) RandomRed(id int) image.Image {
-	for _, p := range fi.xp {
-		if (p.X+p.Y)%2 == 0 {
-			fi.Set(p.X, p.Y, changeColorRgba(0, p.ColorRGBA.G, p.ColorRGBA.B, p.ColorRGBA.A, fi.Factor))
-		}
-	}
-
+	fi.ChannelShift(id, zeroChannelShift("r", fi.Factor))
 	return fi
 }
 
+// RandomGreen zero the green channel and shift every channel by fi.Factor on
+// a checkerboard
 func (fi *FilterImg) RandomGreen(id int) image.Image {
-	for _, p := range fi.xp {
-		if (p.X+p.Y)%2 == 0 {
-			fi.Set(p.X, p.Y, changeColorRgba(p.ColorRGBA.R, 0, p.ColorRGBA.B, p.ColorRGBA.A, fi.Factor))
-		}
-	}
-
+	fi.ChannelShift(id, zeroChannelShift("g", fi.Factor))
 	return fi
 }
 
+// RandomBlue zero the blue channel and shift every channel by fi.Factor on a
+// checkerboard
 func (fi *FilterImg) RandomBlue(id int) image.Image {
-	for _, p := range fi.xp {
-		if (p.X+p.Y)%2 == 0 {
-			fi.Set(p.X, p.Y, changeColorRgba(p.ColorRGBA.R, p.ColorRGBA.G, 0, p.ColorRGBA.A, fi.Factor))
-		}
-	}
-
+	fi.ChannelShift(id, zeroChannelShift("b", fi.Factor))
 	return fi
 }
 
 func (fi *FilterImg) GreyScale(id int) image.Image {
-	for _, p := range fi.xp {
-		originalColor, ok := color.RGBAModel.Convert(p.OriginalColor).(color.RGBA)
-		if !ok {
-			fmt.Println("type conversion went wrong")
-		}
-		grey := uint8(float64(originalColor.R)*0.21 + float64(originalColor.G)*0.72 + float64(originalColor.B)*0.07)
-		grayColor := color.RGBA{
-			grey,
-			grey,
-			grey,
-			originalColor.A,
-		}
-		fi.Set(p.X, p.Y, grayColor)
-	}
+	dst := fi.buffer()
+	fi.rangeParallel(func(x, y int, c color.RGBA) {
+		grey := uint8(float64(c.R)*0.21 + float64(c.G)*0.72 + float64(c.B)*0.07)
+		dst.SetRGBA(x, y, color.RGBA{grey, grey, grey, c.A})
+	})
 	return fi
 }
 
-func (fi *FilterImg) ByteScaleTxtFile(id int) string {
-	var currentY int = -1
-	outFile, _ := os.Create(fi.alias + "_byte.txt")
-	s := time.Now()
-	for _, p := range fi.xp {
-		currentValue := "0"
-		if p.IsLight {
-			currentValue = "1"
-		}
-		if currentY != p.Y {
-			currentY = p.Y
-			if _, err := outFile.Write([]byte("\n" + currentValue)); err != nil {
-				log.Fatal(err)
-				fi.log <- err.Error()
+// TextStyle choose the colors used by MakeFromGrid
+type TextStyle struct {
+	// Color draw every glyph in the average color of its cell instead of
+	// black, or white on a Dark background
+	Color bool
+	// Dark draw the glyphs on a black canvas instead of a white one
+	Dark bool
+}
+
+// textFace draw the characters of MakeFromGrid, the block elements are
+// filled by blockFace
+var textFace = newBlockFace(inconsolata.Bold8x16)
+
+// CheckTextGlyphs return an error for the first character of chars without a
+// glyph in the font of MakeFromGrid, it would be drawn blank
+func CheckTextGlyphs(chars []string) error {
+	for _, s := range chars {
+		for _, r := range s {
+			if !textFace.HasGlyph(r) {
+				return fmt.Errorf("character %q has no glyph in the text image font, use it with the ascii command", r)
 			}
-			continue
-		}
-		if _, err := outFile.Write([]byte(currentValue)); err != nil {
-			log.Fatal(err)
-			fi.log <- err.Error()
 		}
 	}
-	outFile.Close()
-	e := time.Since(s)
-	fi.log <- fmt.Sprintf("byte-scale, task: %d total create txt => %v", id, e)
-	path, err := os.Getwd()
+	return nil
+}
+
+// ByteScaleGrid return a cell per pixel, "1" for the light ones and "0" for
+// the dark ones
+func (fi *FilterImg) ByteScaleGrid(ctx context.Context, id int) (*ascii.Grid, error) {
+	s := time.Now()
+	g, err := ascii.FromPixels(ctx, fi.GetPixels(), ascii.ByteMapper{})
 	if err != nil {
-		fi.log <- fmt.Sprintf("byte-scale, task: %d error %v", id, err)
+		return nil, fmt.Errorf("byte-scale %w", err)
 	}
-	return filepath.Join(path, outFile.Name())
+	e := time.Since(s)
+	fi.AddLog(fmt.Sprintf("byte-scale, task: %d total create grid => %v", id, e))
+	return g, nil
 }
 
-func (fi *FilterImg) MakeFromTxtFile(txtFilePath string) (image.Image, error) {
+// MakeFromGrid draw the rows of g on a canvas 20% wider and 24% taller than
+// the image, black on white unless style say otherwise
+func (fi *FilterImg) MakeFromGrid(ctx context.Context, g *ascii.Grid, style TextStyle) (image.Image, error) {
 	bounds := fi.Bounds()
 	width, height := bounds.Max.X, bounds.Max.Y
 	img := image.NewRGBA(image.Rect(0, 0, width+((20*width)/100), height+((24*height)/100)))
 
-	draw.Draw(img, img.Bounds(), &image.Uniform{color.White}, image.ZP, draw.Src)
-
-	x, y := 10, 10
-	col := color.Black
-
-	f, err := os.Open(txtFilePath)
-	if err != nil {
-		return nil, err
+	background, col := color.Color(color.White), color.Color(color.Black)
+	if style.Dark {
+		background, col = color.Black, color.White
 	}
+	draw.Draw(img, img.Bounds(), &image.Uniform{background}, image.ZP, draw.Src)
 
-	scanner := bufio.NewScanner(f)
-	scanner.Split(bufio.ScanLines)
+	// the first row start one line below the top margin
+	x, y := 10, 22
+	src := image.NewUniform(col)
 
-	for scanner.Scan() {
+	rows := progress.NewRows(fi.observer, g.Rows)
+	for row, line := range g.Lines() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		point := fixed.Point26_6{X: fixed.Int26_6(x), Y: fixed.Int26_6(y * 44)}
 		d := font.Drawer{
 			Dst:  img,
-			Src:  image.NewUniform(col),
-			Face: inconsolata.Bold8x16,
+			Src:  src,
+			Face: textFace,
 			Dot:  point,
 		}
-		d.DrawString(scanner.Text())
+		if !style.Color {
+			d.DrawString(line)
+			y += 12
+			rows.Done(1)
+			continue
+		}
+		// a glyph at a time in the color of its cell, the rest of the row
+		// is out of the canvas
+		for i := 0; i < g.Cols && d.Dot.X.Floor() < img.Rect.Max.X; i++ {
+			cell := g.At(i, row)
+			src.C = cell.Color
+			d.DrawString(cell.Char)
+		}
 		y += 12
-	}
-	f.Close()
-	err = os.Remove(txtFilePath)
-	if err != nil {
-		return nil, err
+		rows.Done(1)
 	}
 	return img, nil
 }
@@ -0,0 +1,108 @@
+package imagefilter
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/victorvbello/img-processing/pixelextract"
+)
+
+// testImage return a w x h image with a different color on every pixel
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(x * 7), uint8(y * 13), uint8(x*y + 40), uint8(200 + x%56)})
+		}
+	}
+	return img
+}
+
+func TestFilterImgCopyOnWrite(t *testing.T) {
+	src := testImage(16, 9)
+	original := append([]uint8(nil), src.Pix...)
+	fi := NewFilterImg("", src, nil, 0, nil)
+	fi.GreyScale(0)
+	fi.Set(1, 1, color.RGBA{1, 2, 3, 4})
+	for i := range src.Pix {
+		if src.Pix[i] != original[i] {
+			t.Fatalf("source changed at byte %d", i)
+		}
+	}
+	if got := fi.At(1, 1); got != (color.RGBA{1, 2, 3, 4}) {
+		t.Errorf("At(1, 1) = %v, want the set color", got)
+	}
+	if fi.RGBA() == src {
+		t.Error("RGBA returned the source instead of a copy")
+	}
+}
+
+func TestFilterImgInPlace(t *testing.T) {
+	src := testImage(16, 9)
+	fi := NewFilterImgInPlace("", src, nil, 0, nil)
+	fi.Set(2, 3, color.RGBA{9, 8, 7, 6})
+	if got := src.RGBAAt(2, 3); got != (color.RGBA{9, 8, 7, 6}) {
+		t.Errorf("source At(2, 3) = %v, want the set color", got)
+	}
+	fi.GreyScale(0)
+	c := src.RGBAAt(5, 5)
+	if c.R != c.G || c.G != c.B {
+		t.Errorf("source pixel %v is not grey", c)
+	}
+	if fi.RGBA() != src {
+		t.Error("RGBA did not return the source")
+	}
+}
+
+// mapImg is the map backed store FilterImg used before the RGBA buffer
+type mapImg struct {
+	image.Image
+	custom map[image.Point]color.Color
+}
+
+func (mi *mapImg) Set(x, y int, c color.Color) {
+	mi.custom[image.Point{x, y}] = c
+}
+
+func (mi *mapImg) At(x, y int) color.Color {
+	if c := mi.custom[image.Point{x, y}]; c != nil {
+		return c
+	}
+	return mi.Image.At(x, y)
+}
+
+// readAll read every pixel like an encoder does
+func readAll(img image.Image) {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			img.At(x, y).RGBA()
+		}
+	}
+}
+
+func BenchmarkGreyScale(b *testing.B) {
+	src := testImage(500, 400)
+	b.Run("rgba", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			fi := NewFilterImg("", src, nil, 0, nil)
+			fi.Workers = 1
+			fi.GreyScale(0)
+			readAll(fi.RGBA())
+		}
+	})
+	b.Run("map", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			mi := &mapImg{src, map[image.Point]color.Color{}}
+			pixelextract.Extract(src).Range(func(x, y int, c color.RGBA) bool {
+				grey := uint8(float64(c.R)*0.21 + float64(c.G)*0.72 + float64(c.B)*0.07)
+				mi.Set(x, y, color.RGBA{grey, grey, grey, c.A})
+				return true
+			})
+			readAll(mi)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package imagefilter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	PNG_HEADER_LEN = 8 + 25 // signature and IHDR chunk
+	JPEG_SOI_LEN   = 2
+	GIF_TRAILER    = 0x3b
+)
+
+// encodeWithMetadata encode img to a buffer and write it to w with the text
+// of o.Metadata: a tEXt chunk per key for png and a comment for jpeg and gif,
+// bmp and tiff are written without it
+func encodeWithMetadata(w io.Writer, img image.Image, o Options) error {
+	var buf bytes.Buffer
+	if err := encodeFormat(&buf, img, o); err != nil {
+		return err
+	}
+	b := buf.Bytes()
+	keys := make([]string, 0, len(o.Metadata))
+	for k := range o.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var meta []byte
+	switch normalizeFormat(o.Format) {
+	case "png":
+		for _, k := range keys {
+			meta = append(meta, pngTextChunk(k, o.Metadata[k])...)
+		}
+		return writeParts(w, b[:PNG_HEADER_LEN], meta, b[PNG_HEADER_LEN:])
+	case "jpeg":
+		comment, err := jpegComment(metadataText(keys, o.Metadata))
+		if err != nil {
+			return err
+		}
+		return writeParts(w, b[:JPEG_SOI_LEN], comment, b[JPEG_SOI_LEN:])
+	case "gif":
+		if len(b) == 0 || b[len(b)-1] != GIF_TRAILER {
+			return errors.New("gif without trailer")
+		}
+		comment := gifComment(metadataText(keys, o.Metadata))
+		return writeParts(w, b[:len(b)-1], comment, b[len(b)-1:])
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func writeParts(w io.Writer, parts ...[]byte) error {
+	for _, p := range parts {
+		if _, err := w.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metadataText join the metadata as "key: value" lines
+func metadataText(keys []string, m map[string]string) string {
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = k + ": " + m[k]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func pngTextChunk(key, value string) []byte {
+	data := append(append([]byte(key), 0), value...)
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk[:4], uint32(len(data)))
+	copy(chunk[4:8], "tEXt")
+	chunk = append(chunk, data...)
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(chunk[4:]))
+	return append(chunk, crc...)
+}
+
+func jpegComment(text string) ([]byte, error) {
+	if len(text) > 0xffff-2 {
+		return nil, errors.New("jpeg comment too long")
+	}
+	comment := []byte{0xff, 0xfe, 0, 0}
+	binary.BigEndian.PutUint16(comment[2:], uint16(len(text)+2))
+	return append(comment, text...), nil
+}
+
+// gifComment make a comment extension, the text is split in sub-blocks of
+// 255 bytes at most
+func gifComment(text string) []byte {
+	comment := []byte{0x21, 0xfe}
+	for len(text) > 0 {
+		n := len(text)
+		if n > 255 {
+			n = 255
+		}
+		comment = append(comment, byte(n))
+		comment = append(comment, text[:n]...)
+		text = text[n:]
+	}
+	return append(comment, 0)
+}
@@ -0,0 +1,94 @@
+package imagefilter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+// pngText read the tEXt chunks of a png, checking the crc of every chunk
+func pngText(t *testing.T, b []byte) map[string]string {
+	text := map[string]string{}
+	for b = b[8:]; len(b) >= 12; {
+		n := int(binary.BigEndian.Uint32(b[:4]))
+		chunk := b[4 : 8+n]
+		if crc := binary.BigEndian.Uint32(b[8+n : 12+n]); crc != crc32.ChecksumIEEE(chunk) {
+			t.Fatalf("bad crc for chunk %q", chunk[:4])
+		}
+		if string(chunk[:4]) == "tEXt" {
+			kv := strings.SplitN(string(chunk[4:]), "\x00", 2)
+			text[kv[0]] = kv[1]
+		}
+		b = b[12+n:]
+	}
+	return text
+}
+
+// jpegCommentText read the COM segment before the start of scan
+func jpegCommentText(b []byte) string {
+	for i := 2; i+4 <= len(b) && b[i] == 0xff; {
+		marker, n := b[i+1], int(binary.BigEndian.Uint16(b[i+2:]))
+		if marker == 0xfe {
+			return string(b[i+4 : i+2+n])
+		}
+		if marker == 0xda {
+			break
+		}
+		i += 2 + n
+	}
+	return ""
+}
+
+// gifCommentText join the sub-blocks of the comment extension
+func gifCommentText(b []byte) string {
+	i := bytes.Index(b, []byte{0x21, 0xfe})
+	if i < 0 {
+		return ""
+	}
+	var text []byte
+	for i += 2; i < len(b) && b[i] != 0; i += 1 + int(b[i]) {
+		text = append(text, b[i+1:i+1+int(b[i])]...)
+	}
+	return string(text)
+}
+
+func TestEncodeWithMetadata(t *testing.T) {
+	src := testImage(16, 8)
+	meta := map[string]string{
+		"Seed": "42",
+		// longer than a gif sub-block
+		"Filters": strings.Repeat("channel-shift factor=90, ", 20),
+	}
+	want := "Filters: " + meta["Filters"] + "\nSeed: 42"
+	for _, format := range []string{"png", "jpeg", "gif"} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, src, Options{Format: format, Metadata: meta}); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		img, name, err := Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: decode: %v", format, err)
+		}
+		if name != format || img.Bounds() != src.Bounds() {
+			t.Errorf("%s: decoded %s %v", format, name, img.Bounds())
+		}
+		b := buf.Bytes()
+		switch format {
+		case "png":
+			text := pngText(t, b)
+			if text["Seed"] != meta["Seed"] || text["Filters"] != meta["Filters"] {
+				t.Errorf("png: text %v", text)
+			}
+		case "jpeg":
+			if got := jpegCommentText(b); got != want {
+				t.Errorf("jpeg: comment %q", got)
+			}
+		case "gif":
+			if got := gifCommentText(b); got != want {
+				t.Errorf("gif: comment %q", got)
+			}
+		}
+	}
+}
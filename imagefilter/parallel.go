@@ -0,0 +1,57 @@
+package imagefilter
+
+import (
+	"context"
+	"image"
+	"runtime"
+	"sync"
+)
+
+type workersKey struct{}
+
+// WithWorkers set the number of goroutines the filters use on ctx, n < 1
+// use GOMAXPROCS
+func WithWorkers(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, workersKey{}, n)
+}
+
+// Workers return the number of goroutines set by WithWorkers, GOMAXPROCS by
+// default
+func Workers(ctx context.Context) int {
+	if n, ok := ctx.Value(workersKey{}).(int); ok && n > 0 {
+		return n
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// parallelRows split bounds in horizontal bands and call fn with each band
+// on up to workers goroutines, the bands never overlap so fn can write its
+// own rows without locks
+func parallelRows(bounds image.Rectangle, workers int, fn func(band image.Rectangle)) {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	height := bounds.Dy()
+	if workers > height {
+		workers = height
+	}
+	if workers <= 1 {
+		fn(bounds)
+		return
+	}
+
+	var wg sync.WaitGroup
+	bandHeight := (height + workers - 1) / workers
+	for minY := bounds.Min.Y; minY < bounds.Max.Y; minY += bandHeight {
+		maxY := minY + bandHeight
+		if maxY > bounds.Max.Y {
+			maxY = bounds.Max.Y
+		}
+		wg.Add(1)
+		go func(band image.Rectangle) {
+			defer wg.Done()
+			fn(band)
+		}(image.Rect(bounds.Min.X, minY, bounds.Max.X, maxY))
+	}
+	wg.Wait()
+}
@@ -0,0 +1,78 @@
+package imagefilter
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"testing"
+
+	"github.com/victorvbello/img-processing/dither"
+	"github.com/victorvbello/img-processing/threshold"
+)
+
+func TestParallelRows(t *testing.T) {
+	bounds := image.Rect(3, -2, 10, 21)
+	for _, workers := range []int{1, 2, 5, 23, 100} {
+		rows := make([]int, bounds.Dy())
+		parallelRows(bounds, workers, func(band image.Rectangle) {
+			if band.Min.X != bounds.Min.X || band.Max.X != bounds.Max.X {
+				t.Errorf("workers %d: band %v is not full width", workers, band)
+			}
+			for y := band.Min.Y; y < band.Max.Y; y++ {
+				rows[y-bounds.Min.Y]++
+			}
+		})
+		for i, n := range rows {
+			if n != 1 {
+				t.Errorf("workers %d: row %d filtered %d times", workers, i+bounds.Min.Y, n)
+			}
+		}
+	}
+}
+
+// TestFiltersWorkers check every filter make the same pixels with one worker
+// and with many
+func TestFiltersWorkers(t *testing.T) {
+	src := testImage(37, 29)
+	filters := map[string]Filter{
+		"grayscale":    GreyScale{},
+		"random-color": RandomColor{Factor: 77},
+		"random-red":   RandomRed{Factor: 77},
+		"random-green": RandomGreen{Factor: 77},
+		"random-blue":  RandomBlue{Factor: 77},
+		"byte":         ByteScale{Scale: 20},
+		"dither":       Dither{Method: dither.FLOYD_STEINBERG, Levels: 2},
+		"threshold":    Threshold{threshold.Options{Method: threshold.MEAN, Window: 5}},
+	}
+	for _, mask := range []string{MASK_ALL, MASK_CHECKERBOARD, MASK_STRIPES, MASK_NOISE} {
+		filters["channel-shift "+mask] = ChannelShift{Factor: 90, Channels: "rb", Zero: "g", Order: "bgra", Mask: mask, Size: 3, Seed: 5}
+	}
+	for name, f := range filters {
+		one, err := f.Apply(WithWorkers(context.Background(), 1), src)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, workers := range []int{2, 7, 64} {
+			many, err := f.Apply(WithWorkers(context.Background(), workers), src)
+			if err != nil {
+				t.Fatalf("%s workers %d: %v", name, workers, err)
+			}
+			if !bytes.Equal(pixels(one), pixels(many)) {
+				t.Errorf("%s: workers %d differ from workers 1", name, workers)
+			}
+		}
+	}
+}
+
+// pixels return the RGBA bytes of img
+func pixels(img image.Image) []uint8 {
+	b := img.Bounds()
+	p := make([]uint8, 0, 4*b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := img.At(x, y).RGBA()
+			p = append(p, uint8(r>>8), uint8(g>>8), uint8(bl>>8), uint8(a>>8))
+		}
+	}
+	return p
+}
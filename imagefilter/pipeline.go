@@ -0,0 +1,82 @@
+package imagefilter
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/victorvbello/img-processing/progress"
+)
+
+// Pipeline chain filters, the output of each step is the input of the next one
+type Pipeline struct {
+	filters  []Filter
+	observer progress.Observer
+}
+
+// NewPipeline make a pipeline reporting its steps to o, a nil o use the
+// observer of the context given to Apply
+func NewPipeline(o progress.Observer, filters ...Filter) *Pipeline {
+	return &Pipeline{filters, o}
+}
+
+func (p *Pipeline) Add(filters ...Filter) *Pipeline {
+	p.filters = append(p.filters, filters...)
+	return p
+}
+
+func (p *Pipeline) Len() int {
+	return len(p.filters)
+}
+
+// stepObserver forward the events of the filter of a step, the percent of
+// the filter become a part of the percent of the pipeline
+type stepObserver struct {
+	progress.Observer
+	stage       string
+	step, steps int
+}
+
+func (so stepObserver) Event(e progress.Event) {
+	if e.Stage == "" {
+		e.Stage = so.stage
+	}
+	e.Percent = progress.Percent(so.step, so.steps) + e.Percent/float64(so.steps)
+	e.Step, e.Steps = so.step, so.steps
+	so.Observer.Event(e)
+}
+
+// Apply run every filter in order, a Pipeline is itself a Filter. When the
+// image is an *Animation each filter is applied to every frame
+func (p *Pipeline) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	var err error
+	o := p.observer
+	if o == nil {
+		o = progress.FromContext(ctx)
+	}
+	steps := len(p.filters)
+	for i, f := range p.filters {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		stage := fmt.Sprintf("step %d %T", i, f)
+		progress.Notify(o, progress.Event{Kind: progress.STARTED, Stage: stage, Step: i, Steps: steps, Percent: progress.Percent(i, steps)})
+		stepCtx := ctx
+		if o != nil {
+			stepCtx = progress.WithObserver(ctx, stepObserver{o, stage, i, steps})
+		}
+		s := time.Now()
+		if a, ok := img.(*Animation); ok {
+			img, err = ApplyFrames(stepCtx, f, a)
+		} else {
+			img, err = f.Apply(stepCtx, img)
+		}
+		if err != nil {
+			progress.Notify(o, progress.Event{Kind: progress.FAILED, Stage: stage, Step: i, Steps: steps, Percent: progress.Percent(i, steps), Message: err.Error()})
+			return nil, fmt.Errorf("step %d %T: %w", i, f, err)
+		}
+		progress.Notify(o, progress.Event{Kind: progress.DONE, Stage: stage, Step: i, Steps: steps, Percent: progress.Percent(i+1, steps), Duration: time.Since(s)})
+	}
+	return img, nil
+}
@@ -0,0 +1,134 @@
+package imagefilter
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// MAX_QUANTIZE_SAMPLES limit the pixels read by MedianCutQuantizer, bigger
+// images are sampled on a regular grid
+const MAX_QUANTIZE_SAMPLES = 1 << 18
+
+// MedianCutQuantizer build an adaptive palette splitting the color space of
+// the image at the median of its widest channel, it implements draw.Quantizer
+type MedianCutQuantizer struct{}
+
+type colorBox []color.RGBA
+
+func (cb colorBox) widest() (int, uint8) {
+	var min, max [3]uint8
+	min = [3]uint8{255, 255, 255}
+	for _, c := range cb {
+		for i, v := range [3]uint8{c.R, c.G, c.B} {
+			if v < min[i] {
+				min[i] = v
+			}
+			if v > max[i] {
+				max[i] = v
+			}
+		}
+	}
+	channel := 0
+	for i := 1; i < 3; i++ {
+		if max[i]-min[i] > max[channel]-min[channel] {
+			channel = i
+		}
+	}
+	return channel, max[channel] - min[channel]
+}
+
+func (cb colorBox) average() color.RGBA {
+	var r, g, b int
+	for _, c := range cb {
+		r += int(c.R)
+		g += int(c.G)
+		b += int(c.B)
+	}
+	n := len(cb)
+	return color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), 255}
+}
+
+func channelValue(c color.RGBA, channel int) uint8 {
+	switch channel {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	}
+	return c.B
+}
+
+func (MedianCutQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	n := cap(p) - len(p)
+	if n < 1 {
+		return p
+	}
+	bounds := m.Bounds()
+	step := 1
+	if total := bounds.Dx() * bounds.Dy(); total > MAX_QUANTIZE_SAMPLES {
+		step = int(math.Ceil(math.Sqrt(float64(total) / MAX_QUANTIZE_SAMPLES)))
+	}
+
+	transparent := false
+	box := make(colorBox, 0, (bounds.Dx()/step+1)*(bounds.Dy()/step+1))
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			c := color.NRGBAModel.Convert(m.At(x, y)).(color.NRGBA)
+			if c.A == 0 {
+				transparent = true
+				continue
+			}
+			box = append(box, color.RGBA{c.R, c.G, c.B, 255})
+		}
+	}
+	if transparent {
+		p = append(p, color.Transparent)
+		n--
+	}
+	if len(box) == 0 || n < 1 {
+		return p
+	}
+
+	boxes := []colorBox{box}
+	for len(boxes) < n {
+		index, channel, span := -1, 0, uint8(0)
+		for i, b := range boxes {
+			if len(b) < 2 {
+				continue
+			}
+			if c, s := b.widest(); s > span {
+				index, channel, span = i, c, s
+			}
+		}
+		if index == -1 {
+			break
+		}
+		b := boxes[index]
+		sort.Slice(b, func(i, j int) bool {
+			return channelValue(b[i], channel) < channelValue(b[j], channel)
+		})
+		mid := len(b) / 2
+		boxes[index] = b[:mid]
+		boxes = append(boxes, b[mid:])
+	}
+
+	for _, b := range boxes {
+		p = append(p, b.average())
+	}
+	return p
+}
+
+// paletteQuantizer always return the same palette
+type paletteQuantizer color.Palette
+
+func (pq paletteQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	for _, c := range pq {
+		if len(p) == cap(p) {
+			break
+		}
+		p = append(p, c)
+	}
+	return p
+}
@@ -0,0 +1,19 @@
+package imagefilter
+
+import (
+	"hash/fnv"
+	"math/rand"
+)
+
+// NewRand return the random source of the filter called name, the same seed
+// and name always give the same values whatever the other filters draw
+func NewRand(seed int64, name string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return rand.New(rand.NewSource(seed ^ int64(h.Sum64())))
+}
+
+// RandomFactor draw the factor of the random color filters from rnd
+func RandomFactor(rnd *rand.Rand) uint8 {
+	return uint8(rnd.Intn(255))
+}
@@ -0,0 +1,59 @@
+package imagefilter
+
+import (
+	"context"
+	"fmt"
+	"image"
+
+	"github.com/victorvbello/img-processing/imagetransforms"
+)
+
+// MAX_SCALE is the highest reduction of Resize, 100 percent would leave an
+// empty image
+const MAX_SCALE = 99
+
+// CheckScale return an error when the reduction scale is not between 0 and
+// MAX_SCALE percent
+func CheckScale(scale int) error {
+	if scale < 0 || scale > MAX_SCALE {
+		return fmt.Errorf("scale %d out of range 0 to %d", scale, MAX_SCALE)
+	}
+	return nil
+}
+
+func resize(ctx context.Context, img image.Image, scale int) (image.Image, error) {
+	if err := CheckScale(scale); err != nil {
+		return nil, err
+	}
+	if scale == 0 {
+		return img, nil
+	}
+	return imagetransforms.Resize(ctx, img, scale)
+}
+
+// Resize reduce the image by Scale percent
+type Resize struct {
+	Scale int
+}
+
+func (r Resize) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	return resize(ctx, img, r.Scale)
+}
+
+// Transparency draw the image over a white background using Alpha as mask
+type Transparency struct {
+	Alpha uint8
+}
+
+func (t Transparency) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	return imagetransforms.Transparency(ctx, img, t.Alpha)
+}
+
+// Rotate rotate the image by Angle degrees over a transparent canvas of the same bounds
+type Rotate struct {
+	Angle float64
+}
+
+func (r Rotate) Apply(ctx context.Context, img image.Image) (image.Image, error) {
+	return imagetransforms.Rotate(ctx, image.NewRGBA(img.Bounds()), img, r.Angle)
+}
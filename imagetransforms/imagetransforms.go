@@ -1,6 +1,7 @@
 package imagetransforms
 
 import (
+	"context"
 	"image"
 	"image/color"
 	"math"
@@ -48,13 +49,16 @@ func calculateNewBounds(aff f64.Aff3, b image.Rectangle) image.Rectangle {
 	return image.Rectangle{Min: min, Max: max}
 }
 
-func Transparency(img image.Image, alpha uint8) image.Image {
+func Transparency(ctx context.Context, img image.Image, alpha uint8) (image.Image, error) {
 	bounds := img.Bounds()
 	width, height := bounds.Max.X, bounds.Max.Y
 	resultImg := image.NewRGBA(bounds)
 	mask := image.NewAlpha(bounds)
 	bg := image.NewRGBA(bounds)
 	for x := 0; x < width; x++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		for y := 0; y < height; y++ {
 			bg.Set(x, y, image.White)
 			mask.SetAlpha(x, y, color.Alpha{alpha})
@@ -62,10 +66,13 @@ func Transparency(img image.Image, alpha uint8) image.Image {
 	}
 	draw.Draw(resultImg, bounds, bg, image.ZP, draw.Src)
 	draw.DrawMask(resultImg, bounds, img, image.ZP, mask, image.ZP, draw.Over)
-	return resultImg
+	return resultImg, nil
 }
 
-func Rotate(baseImg image.Image, imgToRotate image.Image, angle float64) image.Image {
+func Rotate(ctx context.Context, baseImg image.Image, imgToRotate image.Image, angle float64) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	angleSin, angleCos := math.Sincos(math.Pi * angle / 180)
 
 	xf, yf := float64(1), float64(1)
@@ -86,15 +93,18 @@ func Rotate(baseImg image.Image, imgToRotate image.Image, angle float64) image.I
 
 	draw.ApproxBiLinear.Transform(rotateImg, matrix, imgToRotate, imgToRotate.Bounds(), draw.Src, nil)
 	draw.Draw(copyBaseImg, centerBounds, rotateImg, rotateImg.Bounds().Min, draw.Over)
-	return copyBaseImg
+	return copyBaseImg, nil
 }
 
-func Resize(img image.Image, scale int) image.Image {
+func Resize(ctx context.Context, img image.Image, scale int) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	bounds := img.Bounds()
 	width, height := bounds.Max.X, bounds.Max.Y
 	newWidth := width - (scale*width)/100
 	newHeight := height - (scale*height)/100
 	newImg := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
 	draw.ApproxBiLinear.Scale(newImg, newImg.Rect, img, bounds, draw.Over, nil)
-	return newImg
+	return newImg, nil
 }
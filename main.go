@@ -1,21 +1,30 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"image"
+	"image/jpeg"
+	"io"
 	"log"
-	"math/rand"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"sync"
+	"runtime"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/urfave/cli/v2"
+	"github.com/victorvbello/img-processing/batch"
+	"github.com/victorvbello/img-processing/dither"
 	"github.com/victorvbello/img-processing/experiment"
 	"github.com/victorvbello/img-processing/imagefilter"
-	"github.com/victorvbello/img-processing/imagetransforms"
-	"github.com/victorvbello/img-processing/pixelextract"
+	"github.com/victorvbello/img-processing/progress"
+	"github.com/victorvbello/img-processing/recipe"
+	"github.com/victorvbello/img-processing/threshold"
+	"github.com/victorvbello/img-processing/utils/naming"
 )
 
 const (
@@ -23,21 +32,116 @@ const (
 	OUTPUT_DIR = "./files/unpublished/"
 )
 
+// logOut receive the processing logs, stderr when the image is written to stdout
+var logOut io.Writer = os.Stdout
+
+// runSeed is the --seed flag or a seed picked from the time, every random
+// filter of the run draw from it
+var runSeed int64
+
+// events render the progress of the filters using --log-format
+var events renderer = &plainRenderer{w: os.Stdout, logs: os.Stderr}
+
 func main() {
-	rand.Seed(time.Now().UnixNano())
+	// SIGINT and SIGTERM cancel the filters, the partial outputs are removed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	cancelTimeout := context.CancelFunc(func() {})
 	app := &cli.App{
+		Before: func(c *cli.Context) error {
+			// the image or the text go to stdout, the logs to stderr
+			if c.String("output") == "-" || (c.Args().First() == "ascii" && !c.IsSet("output")) {
+				logOut = os.Stderr
+			}
+			r, err := newRenderer(c.String("log-format"), logOut)
+			if err != nil {
+				return err
+			}
+			events = r
+			log.SetOutput(events)
+			runSeed = c.Int64("seed")
+			if runSeed == 0 {
+				runSeed = time.Now().UnixNano()
+			}
+			if timeout := c.Duration("timeout"); timeout > 0 {
+				c.Context, cancelTimeout = context.WithTimeout(c.Context, timeout)
+			}
+			return nil
+		},
+		After: func(c *cli.Context) error {
+			return events.Close()
+		},
 		Flags: []cli.Flag{
 			&cli.StringFlag{
-				Name:     "alias",
-				Aliases:  []string{"a"},
-				Usage:    "Alias of file",
-				Required: true,
+				Name:    "alias",
+				Aliases: []string{"a"},
+				Usage:   "Alias of file",
+			},
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Usage:   "Original file path, - read from stdin",
+			},
+			&cli.StringFlag{
+				Name:  "out-dir",
+				Usage: "Directory where the new images are written",
+				Value: OUTPUT_DIR,
+			},
+			&cli.StringFlag{
+				Name:  "name-template",
+				Usage: "Output file name, placeholders: {alias} {filter} {name} {srcext} {ext} {w} {h}",
+				Value: DEFAULT_NAME_TEMPLATE,
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Output file path, replace out-dir and name-template, - write to stdout",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Output format (jpeg, jpg, png, gif, bmp, tif, tiff), by default the input one",
+			},
+			&cli.IntFlag{
+				Name:  "jpeg-quality",
+				Usage: "JPEG quality from 1 to 100",
+				Value: jpeg.DefaultQuality,
+			},
+			&cli.StringFlag{
+				Name:  "png-compression",
+				Usage: "PNG compression level: default, none, speed, best",
+				Value: "default",
+			},
+			&cli.StringFlag{
+				Name:  "gif-palette",
+				Usage: "GIF palette: plan9, websafe, adaptive",
+				Value: "plan9",
+			},
+			&cli.BoolFlag{
+				Name:  "gif-dither",
+				Usage: "GIF Floyd-Steinberg dithering",
+				Value: true,
+			},
+			&cli.StringFlag{
+				Name:  "tiff-compression",
+				Usage: "TIFF compression: none, deflate",
+				Value: "none",
+			},
+			&cli.IntFlag{
+				Name:  "workers",
+				Usage: "Number of row bands of an image filtered at the same time",
+				Value: runtime.GOMAXPROCS(0),
+			},
+			&cli.Int64Flag{
+				Name:  "seed",
+				Usage: "Seed of the random filters, the same seed make the same image, 0 pick one from the time",
 			},
 			&cli.StringFlag{
-				Name:     "file",
-				Aliases:  []string{"f"},
-				Usage:    "Original file path",
-				Required: true,
+				Name:  "log-format",
+				Usage: "Progress output: plain, json (one event per line), bar",
+				Value: "plain",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "Stop the processing after this time (e.g. 30s, 2m), 0 never stop",
 			},
 		},
 		Commands: []*cli.Command{
@@ -47,548 +151,653 @@ func main() {
 				Action: func(c *cli.Context) error {
 					s := time.Now()
 					log.Println("----ALL INIT----")
-					alias := c.String("alias")
-					inputFile := c.String("file")
-					byteImgProcessing(alias, inputFile)
-					characterImgProcessing(alias, inputFile)
-					grayScaleImgProcessing(alias, inputFile)
-					randomColorImgProcessing(alias, inputFile)
-					randomColorRedImgProcessing(alias, inputFile)
-					randomColorGreenImgProcessing(alias, inputFile)
-					randomColorBlueImgProcessing(alias, inputFile)
+					alias, inputFile, err := inputFlags(c)
+					if err != nil {
+						return err
+					}
+					out := outputFromFlags(c, DEFAULT_NAME_TEMPLATE)
+					if out.file != "" {
+						return errors.New("all: --output can not be used, there is one image per filter")
+					}
+					// the input is decoded once, stdin can only be read by the first filter
+					ext := inputExt(inputFile)
+					if err := out.validate(ext); err != nil {
+						return err
+					}
+					img, err := decodeInput(inputFile)
+					if err != nil {
+						return err
+					}
+					var errs errorList
+					for _, fileProcessFlag := range []string{
+						"byte",
+						"character",
+						"grayscale",
+						"random_color",
+						"random_color_red",
+						"random_color_green",
+						"random_color_blue",
+					} {
+						filters, err := commandFilters(c, alias, fileProcessFlag)
+						if err != nil {
+							errs = append(errs, fmt.Errorf("%s: %w", fileProcessFlag, err))
+							continue
+						}
+						fields := naming.Fields{Alias: alias, Filter: fileProcessFlag, Name: naming.BaseName(inputFile), SrcExt: filepath.Ext(inputFile)}
+						err = filterImg(filterContext(c), img, fields, out, ext, filters...)
+						if err != nil {
+							errs = append(errs, fmt.Errorf("%s: %w", fileProcessFlag, err))
+							if canceled(err) {
+								break
+							}
+						}
+					}
 					log.Println("----ALL END----", time.Since(s))
-					return nil
+					return errs.err()
 				},
 			},
 			{
 				Name:  "character-pixel-weight",
 				Usage: "Character pixel weight to file",
-				Action: func(c *cli.Context) error {
-					experiment.CharacterPixelTypeCountMakeFile("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
-					return nil
-				},
-			},
-			{
-				Name:  "infinite",
-				Usage: "Mane new img infinite",
-				Action: func(c *cli.Context) error {
-					alias := c.String("alias")
-					inputFile := c.String("file")
-					infiniteImgProcessing(alias, inputFile)
-					return nil
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "font",
+						Usage: "TrueType or OpenType font file (.ttf, .otf), by default a 7x13 bitmap font",
+					},
+					&cli.Float64Flag{
+						Name:  "size",
+						Usage: "Font size in points",
+						Value: 12,
+					},
+					&cli.StringFlag{
+						Name:  "charset",
+						Usage: "Characters to measure, any unicode character of the font",
+						Value: "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+					},
+					&cli.StringFlag{
+						Name:  "out",
+						Usage: "Weight table path",
+						Value: experiment.DEFAULT_WEIGHT_FILE,
+					},
 				},
-			},
-			{
-				Name:  "infinite-spiral",
-				Usage: "Mane new img infinite spiral",
 				Action: func(c *cli.Context) error {
-					alias := c.String("alias")
-					inputFile := c.String("file")
-					infiniteSpiralImgProcessing(alias, inputFile)
+					charset := c.String("charset")
+					if charset == "" {
+						return errors.New("character-pixel-weight: --charset can not be empty")
+					}
+					if c.String("font") == "" {
+						return experiment.CharacterPixelTypeCountMakeFile(charset, c.String("out"))
+					}
+					charInfo, err := experiment.FontCharacterInfo(c.String("font"), c.Float64("size"), charset)
+					if err != nil {
+						return err
+					}
+					if err := experiment.SaveCharacterInfo(charInfo, c.String("out")); err != nil {
+						return err
+					}
+					log.Printf("%d characters of %dx%d written to %s", len(charInfo.CharacterData), charInfo.BaseWidth, charInfo.BaseHeight, c.String("out"))
 					return nil
 				},
 			},
 			{
-				Name:  "byte",
-				Usage: "Make new img using a byte filter",
+				Name:      "run",
+				Usage:     "Run the steps of a recipe file (json or yaml)",
+				ArgsUsage: "recipe.json",
 				Action: func(c *cli.Context) error {
-					alias := c.String("alias")
-					inputFile := c.String("file")
-					byteImgProcessing(alias, inputFile)
-					return nil
+					if c.NArg() != 1 {
+						return errors.New("run: recipe file is required")
+					}
+					r, err := recipe.Load(c.Args().First())
+					if err != nil {
+						return err
+					}
+					if c.IsSet("file") {
+						r.Input = c.String("file")
+					}
+					if c.IsSet("alias") {
+						r.Alias = c.String("alias")
+					}
+					if err := r.Validate(); err != nil {
+						return err
+					}
+					if r.Seed == 0 || c.IsSet("seed") {
+						r.Seed = runSeed
+					}
+					filters, err := r.Filters()
+					if err != nil {
+						return err
+					}
+					out := outputFromFlags(c, DEFAULT_NAME_TEMPLATE)
+					out.seed = r.Seed
+					if out.file == "" && r.Output.Path != "" {
+						out.file = out.withExt(r.OutputPath())
+					}
+					fields := naming.Fields{Alias: r.GetAlias(), Filter: "recipe", Name: naming.BaseName(r.Input), SrcExt: filepath.Ext(r.Input)}
+					return processImg(filterContext(c), r.Input, fields, out, r.OutputExt(), filters...)
 				},
 			},
 			{
-				Name:  "character-pixel-color-replace",
-				Usage: "Character pixel color replace",
-				Action: func(c *cli.Context) error {
-					alias := c.String("alias")
-					inputFile := c.String("file")
-					characterImgProcessing(alias, inputFile)
-					return nil
-				},
-			},
-			{
-				Name:  "grayscale",
-				Usage: "Make new img using a greyScale filter",
-				Action: func(c *cli.Context) error {
-					alias := c.String("alias")
-					inputFile := c.String("file")
-					grayScaleImgProcessing(alias, inputFile)
-					return nil
-				},
-			},
-			{
-				Name:  "random-color",
-				Usage: "Make new img using a randomColor filter",
-				Action: func(c *cli.Context) error {
-					alias := c.String("alias")
-					inputFile := c.String("file")
-					randomColorImgProcessing(alias, inputFile)
-					return nil
-				},
-			},
-			{
-				Name:  "random-color-red",
-				Usage: "Make new img using a random color red filter",
-				Action: func(c *cli.Context) error {
-					alias := c.String("alias")
-					inputFile := c.String("file")
-					randomColorRedImgProcessing(alias, inputFile)
-					return nil
-				},
-			},
-			{
-				Name:  "random-color-green",
-				Usage: "Make new img using a random color gree filter",
-				Action: func(c *cli.Context) error {
-					alias := c.String("alias")
-					inputFile := c.String("file")
-					randomColorGreenImgProcessing(alias, inputFile)
-					return nil
-				},
-			},
-			{
-				Name:  "random-color-blue",
-				Usage: "Make new img using a random color blue filter",
-				Action: func(c *cli.Context) error {
-					alias := c.String("alias")
-					inputFile := c.String("file")
-					randomColorBlueImgProcessing(alias, inputFile)
-					return nil
+				Name:  "batch",
+				Usage: "Apply filters to every image of a directory",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "dir",
+						Usage: "Directory to walk",
+						Value: INPUT_DIR,
+					},
+					&cli.StringFlag{
+						Name:  "out",
+						Usage: "Output directory, the tree of dir is mirrored inside, by default out-dir/batch",
+					},
+					&cli.StringSliceFlag{
+						Name:  "include",
+						Usage: "Glob of files to process, matched against the relative path and the file name",
+						Value: cli.NewStringSlice("*.jpg", "*.jpeg", "*.png", "*.gif", "*.bmp", "*.tif", "*.tiff", "*.webp"),
+					},
+					&cli.StringSliceFlag{
+						Name:  "exclude",
+						Usage: "Glob of files to skip",
+					},
+					&cli.StringSliceFlag{
+						Name:  "filter",
+						Usage: "Recipe step to apply with its default params, repeat to chain steps",
+					},
+					&cli.StringFlag{
+						Name:  "recipe",
+						Usage: "Recipe file whose steps are applied, input and output are ignored",
+					},
+					&cli.IntFlag{
+						Name:  "jobs",
+						Usage: "Number of files processed at the same time",
+						Value: runtime.NumCPU(),
+					},
 				},
+				Action: batchAction,
 			},
+			asciiCommand(),
+			filterCommand("infinite", "Mane new img infinite", "infinite", "", animationFlags(10)...),
+			filterCommand("infinite-spiral", "Mane new img infinite spiral", "infinite_spiral", ".png", animationFlags(36)...),
+			filterCommand("byte", "Make new img using a byte filter", "byte", "", append(append(textStyleFlags(), &cli.StringFlag{
+				Name:  "dither",
+				Usage: "Dither the pixels before picking the 1s: " + strings.Join(dither.Methods(), ", "),
+			}, &cli.StringFlag{
+				Name:  "threshold",
+				Usage: "Pick the 1s with a threshold: " + strings.Join(threshold.Methods(), ", "),
+			}), thresholdFlags()...)...),
+			filterCommand("threshold", "Make new black and white img using a threshold", "threshold", ".png", append(thresholdFlags(), &cli.StringFlag{
+				Name:  "method",
+				Usage: "Threshold: fixed (--level), otsu (level from the histogram), mean or gaussian (average of the --window around the pixel minus --offset)",
+				Value: threshold.OTSU,
+			})...),
+			filterCommand("dither", "Make new img with a few gray levels using dithering", "dither", ".png", ditherFlags()...),
+			filterCommand("character-pixel-color-replace", "Character pixel color replace", "character", "", append(textStyleFlags(), &cli.StringFlag{
+				Name:  "weights",
+				Usage: "Embedded weight table (" + strings.Join(experiment.WeightTables(), ", ") + ") or a file made by character-pixel-weight",
+				Value: experiment.DEFAULT_WEIGHT_TABLE,
+			})...),
+			filterCommand("grayscale", "Make new img using a greyScale filter", "grayscale", ""),
+			filterCommand("channel-shift", "Make new img shifting the chosen channels by a factor", "channel_shift", "", channelShiftFlags()...),
+			filterCommand("random-color", "Make new img using a randomColor filter", "random_color", ""),
+			filterCommand("random-color-red", "Make new img using a random color red filter", "random_color_red", ""),
+			filterCommand("random-color-green", "Make new img using a random color gree filter", "random_color_green", ""),
+			filterCommand("random-color-blue", "Make new img using a random color blue filter", "random_color_blue", ""),
 		},
 	}
 
-	err := app.Run(os.Args)
+	err := app.RunContext(ctx, os.Args)
+	cancelTimeout()
+	stop()
 	if err != nil {
-		log.Fatal(err)
+		log.Print(err)
+		os.Exit(exitCode(err))
 	}
 }
 
-func byteImgProcessing(alias string, imgFile string) {
-	var wg sync.WaitGroup
-	fileProcessFlag := "byte"
-	log.Println("process", fileProcessFlag)
-	s := time.Now()
-	img, err := imagefilter.DecodeImg(imgFile)
-	if err != nil {
-		log.Fatal(fmt.Errorf("decode-file %w", err))
-	}
-	xp := pixelextract.ExtractPixelFromImg(img)
-	log.Println("total open ", time.Since(s))
-
-	c := make(chan string)
-
-	t := 2
-
-	filterImg := imagefilter.NewFilterImg(alias, img, xp, 0, c)
-	for i := 1; i < t; i++ {
-		wg.Add(1)
-		go func(id int) {
-			sr := time.Now()
-			newImg := imagetransforms.Resize(filterImg, 85)
-			filterImg.AddLog(fmt.Sprintf("resize, task: %d total resize => %v", id, time.Since(sr).String()))
-			newXp := pixelextract.ExtractPixelFromImg(newImg)
-			ss := time.Now()
-			filterImg.SetXp(newXp)
-			txtFileName := filterImg.ByteScaleTxtFile(id)
-			img, err := filterImg.MakeFromTxtFile(txtFileName)
+// filterCommand build a command that apply the filters of fileProcessFlag,
+// an empty ext keep the extension of the input file
+func filterCommand(name, usage, fileProcessFlag, ext string, flags ...cli.Flag) *cli.Command {
+	return &cli.Command{
+		Name:  name,
+		Usage: usage,
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			alias, inputFile, err := inputFlags(c)
 			if err != nil {
-				filterImg.AddLog("Error txt to img " + err.Error())
+				return err
 			}
-			_, err = imagefilter.EncodeIMG(img, OUTPUT_DIR+alias+"/"+alias+"_"+fileProcessFlag+filepath.Ext(imgFile))
+			filters, err := commandFilters(c, alias, fileProcessFlag)
 			if err != nil {
-				filterImg.AddLog("Error encode img " + err.Error())
-				return
+				return err
 			}
-			filterImg.AddLog("total process " + time.Since(ss).String())
-			wg.Done()
-		}(i)
-	}
-
-	go func() {
-		wg.Wait()
-		close(c)
-	}()
-
-	for l := range c {
-		fmt.Printf("\t%s\n", l)
-	}
-}
-
-func grayScaleImgProcessing(alias string, imgFile string) {
-	var wg sync.WaitGroup
-	fileProcessFlag := "grayscale"
-	log.Println("process", fileProcessFlag)
-	s := time.Now()
-	img, err := imagefilter.DecodeImg(imgFile)
-	if err != nil {
-		log.Fatal(fmt.Errorf("decode-file %w", err))
-	}
-	xp := pixelextract.ExtractPixelFromImg(img)
-	log.Println("total open ", time.Since(s))
-
-	c := make(chan string)
-
-	t := 2
-
-	filterImg := imagefilter.NewFilterImg(alias, img, xp, 0, c)
-
-	for i := 1; i < t; i++ {
-		wg.Add(1)
-		go func(id int) {
-			ss := time.Now()
-			img := filterImg.GreyScale(id)
-			_, err = imagefilter.EncodeIMG(img, OUTPUT_DIR+alias+"/"+alias+"_"+fileProcessFlag+filepath.Ext(imgFile))
-			if err != nil {
-				filterImg.AddLog("Error encode img " + err.Error())
-				return
+			outExt := ext
+			if outExt == "" {
+				outExt = inputExt(inputFile)
 			}
-			filterImg.AddLog("total process " + time.Since(ss).String())
-			wg.Done()
-		}(i)
-	}
-
-	go func() {
-		wg.Wait()
-		close(c)
-	}()
-
-	for l := range c {
-		fmt.Printf("\t%s\n", l)
+			// animations are only kept by the gif encoder, other formats
+			// write a numbered sequence
+			if c.Bool("animate") {
+				outExt = ".gif"
+				if c.Bool("sequence") {
+					outExt = ".png"
+				}
+			}
+			out := outputFromFlags(c, DEFAULT_NAME_TEMPLATE)
+			fields := naming.Fields{Alias: alias, Filter: fileProcessFlag, Name: naming.BaseName(inputFile), SrcExt: filepath.Ext(inputFile)}
+			return processImg(filterContext(c), inputFile, fields, out, outExt, filters...)
+		},
 	}
 }
 
-func characterImgProcessing(alias string, imgFile string) {
-	var wg sync.WaitGroup
-	fileProcessFlag := "character"
-	log.Println("process", fileProcessFlag)
-	sw := time.Now()
-	jsonCharWeight, err := os.Open("./files/unpublished/matrix/charts_weight.txt")
-	if err != nil {
-		log.Fatal(fmt.Errorf("open-weight-file %w", err))
+func batchAction(c *cli.Context) error {
+	var steps []recipe.Step
+	seed := runSeed
+	if c.IsSet("recipe") {
+		r, err := recipe.Load(c.String("recipe"))
+		if err != nil {
+			return err
+		}
+		steps = r.Steps
+		if r.Seed != 0 && !c.IsSet("seed") {
+			seed = r.Seed
+		}
 	}
-	byteValue, err := ioutil.ReadAll(jsonCharWeight)
-	if err != nil {
-		log.Fatal(fmt.Errorf("read-weight-file %w", err))
+	for _, f := range c.StringSlice("filter") {
+		steps = append(steps, recipe.Step{Filter: f})
 	}
-	var charInfo experiment.CharacterInfo
-	err = json.Unmarshal(byteValue, &charInfo)
-	if err != nil {
-		log.Fatal(fmt.Errorf("json-unmarshal-weight %w", err))
+	if len(steps) == 0 {
+		return errors.New("batch: --filter or --recipe is required")
 	}
-	jsonCharWeight.Close()
-	log.Println("total open charts_weight file", time.Since(sw))
-
-	s := time.Now()
-
-	img, err := imagefilter.DecodeImg(imgFile)
-	if err != nil {
-		log.Fatal(fmt.Errorf("decode-file %w", err))
+	stepNames := make([]string, len(steps))
+	for i, s := range steps {
+		stepNames[i] = s.Filter
 	}
-	xp := pixelextract.ExtractPixelFromImg(img)
-	log.Println("total open ", time.Since(s))
-
-	c := make(chan string)
-
-	t := 2
-
-	filterImg := imagefilter.NewFilterImg(alias, img, xp, 0, c)
-	newImg := imagetransforms.Transparency(filterImg, 2)
-	for i := 1; i < t; i++ {
-		wg.Add(1)
-		go func(id int) {
-			sr := time.Now()
-			newImg := imagetransforms.Resize(newImg, 85)
-			newXp := pixelextract.ExtractPixelFromImg(newImg)
-			filterImg.AddLog(fmt.Sprintf("resize, task: %d total resize => %v", id, time.Since(sr).String()))
-			ss := time.Now()
-			filterImg.SetXp(newXp)
-			txtFileName := experiment.CharacterScaleTxtFile(filterImg, id, charInfo)
-			img, err := filterImg.MakeFromTxtFile(txtFileName)
-			if err != nil {
-				filterImg.AddLog("Error txt to img " + err.Error())
-			}
-
-			_, err = imagefilter.EncodeIMG(img, OUTPUT_DIR+alias+"/"+alias+"_"+fileProcessFlag+filepath.Ext(imgFile))
-			if err != nil {
-				filterImg.AddLog("Error encode img " + err.Error())
-				return
-			}
-			filterImg.AddLog("total process " + time.Since(ss).String())
-			wg.Done()
-		}(i)
+	// validate the steps once before walking the directory
+	if _, err := recipe.BuildFilters("batch", steps, seed); err != nil {
+		return err
 	}
 
-	go func() {
-		wg.Wait()
-		close(c)
-	}()
-
-	for l := range c {
-		fmt.Printf("\t%s\n", l)
+	out := outputFromFlags(c, DEFAULT_BATCH_NAME_TEMPLATE)
+	out.seed = seed
+	if out.file != "" {
+		return errors.New("batch: --output can not be used, use --out and --name-template")
 	}
-}
-
-func randomColorImgProcessing(alias string, imgFile string) {
-	var wg sync.WaitGroup
-	fileProcessFlag := "random_color"
-	log.Println("process", fileProcessFlag)
-	s := time.Now()
-	img, err := imagefilter.DecodeImg(imgFile)
-	if err != nil {
-		log.Fatal(fmt.Errorf("decode-file %w", err))
+	// the format of every file is checked again before decoding it
+	if err := out.validate(".png"); err != nil {
+		return err
 	}
-	xp := pixelextract.ExtractPixelFromImg(img)
-	log.Println("total open ", time.Since(s))
-
-	c := make(chan string)
-
-	t := 2
-
-	filterImg := imagefilter.NewFilterImg(alias, img, xp, 0, c)
-
-	for i := 1; i < t; i++ {
-		wg.Add(1)
-		go func(id int) {
-			filterImg.Factor = uint8(rand.Intn(255))
-			filterImg.AddLog(fmt.Sprintf("factor %d", filterImg.Factor))
-			ss := time.Now()
-			img := filterImg.RandomColor(id)
-			_, err = imagefilter.EncodeIMG(img, OUTPUT_DIR+alias+"/"+alias+"_"+fileProcessFlag+filepath.Ext(imgFile))
-			if err != nil {
-				filterImg.AddLog("Error encode img " + err.Error())
-				return
-			}
-			filterImg.AddLog("total process " + time.Since(ss).String())
-			wg.Done()
-		}(i)
+	outDir := c.String("out")
+	if outDir == "" {
+		outDir = filepath.Join(out.dir, "batch")
 	}
+	jobs, err := batch.Walk(c.String("dir"), outDir, c.StringSlice("include"), c.StringSlice("exclude"))
+	if err != nil {
+		return fmt.Errorf("batch-walk %w", err)
+	}
+	log.Printf("batch %d files, %d jobs", len(jobs), c.Int("jobs"))
+
+	process := func(ctx context.Context, job batch.Job) error {
+		alias := strings.NewReplacer(string(filepath.Separator), "_", ".", "_").Replace(job.Rel)
+		filters, err := recipe.BuildFilters(alias, steps, seed)
+		if err != nil {
+			return err
+		}
+		ext := inputExt(job.Input)
+		if err := out.validate(ext); err != nil {
+			return err
+		}
+		img, err := imagefilter.DecodeImg(job.Input)
+		if err != nil {
+			return err
+		}
+		newImg, err := imagefilter.NewPipeline(nil, filters...).Apply(ctx, img)
+		if err != nil {
+			return err
+		}
+		fields := naming.Fields{Alias: alias, Filter: strings.Join(stepNames, "_"), Name: naming.BaseName(job.Rel), SrcExt: filepath.Ext(job.Rel)}
+		jobOut := out
+		if !c.IsSet("name-template") && !strings.EqualFold(fields.SrcExt, out.ext(ext)) {
+			// in.png and in.jpg would both be written as in.<format>
+			jobOut.template = DEFAULT_BATCH_CONVERTED_NAME_TEMPLATE
+		}
+		outFile := jobOut.path(filepath.Dir(job.Output), fields, ext, newImg)
+		enc := out.enc
+		enc.Metadata = out.metadata(filters)
+		return encodeOutput(ctx, newImg, outFile, out.ext(ext), enc)
+	}
+
+	summary := batch.Run(filterContext(c), jobs, c.Int("jobs"), process, events)
+
+	failed := summary.Failed()
+	log.Printf("batch end, %d succeeded, %d failed, total => %v", summary.Succeeded(), len(failed), summary.Duration)
+	var errs errorList
+	for _, r := range failed {
+		errs = append(errs, inputError(r.Input, r.Err))
+	}
+	return errs.err()
+}
 
-	go func() {
-		wg.Wait()
-		close(c)
-	}()
-
-	for l := range c {
-		fmt.Printf("\t%s\n", l)
+// channelShiftFlags are the params of imagefilter.ChannelShift
+func channelShiftFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.UintFlag{
+			Name:  "factor",
+			Usage: "Value subtracted from the channels, 0 to 255, random from --seed when not set",
+		},
+		&cli.StringFlag{
+			Name:  "channels",
+			Usage: "Channels shifted by the factor, any of r, g, b, a",
+			Value: "rgb",
+		},
+		&cli.StringFlag{
+			Name:  "zero",
+			Usage: "Channels set to 0 before the shift, any of r, g, b, a",
+		},
+		&cli.StringFlag{
+			Name:  "order",
+			Usage: "Source channel of r, g, b and a, e.g. bgra swap red and blue",
+			Value: imagefilter.CHANNELS,
+		},
+		&cli.StringFlag{
+			Name:  "mask",
+			Usage: "Pixels changed: all, checkerboard, stripes, noise",
+			Value: imagefilter.MASK_ALL,
+		},
+		&cli.IntFlag{
+			Name:  "size",
+			Usage: "Size in pixels of the checkerboard squares and the stripes",
+			Value: 1,
+		},
+		&cli.BoolFlag{
+			Name:  "clamp",
+			Usage: "Stop the shift at 0 instead of wrapping around",
+		},
 	}
 }
 
-func randomColorRedImgProcessing(alias string, imgFile string) {
-	var wg sync.WaitGroup
-	fileProcessFlag := "random_color_red"
-	log.Println("process", fileProcessFlag)
-	s := time.Now()
-	img, err := imagefilter.DecodeImg(imgFile)
-	if err != nil {
-		log.Fatal(fmt.Errorf("decode-file %w", err))
+func ditherFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "method",
+			Usage: "Dithering: " + strings.Join(dither.Methods(), ", "),
+			Value: dither.FLOYD_STEINBERG,
+		},
+		&cli.IntFlag{
+			Name:  "levels",
+			Usage: "Gray levels of the output, 2 write a 1-bit png",
+			Value: 2,
+		},
 	}
-	xp := pixelextract.ExtractPixelFromImg(img)
-	log.Println("total open ", time.Since(s))
-
-	c := make(chan string)
-
-	t := 2
-
-	filterImg := imagefilter.NewFilterImg(alias, img, xp, 0, c)
+}
 
-	for i := 1; i < t; i++ {
-		wg.Add(1)
-		go func(id int) {
-			filterImg.Factor = uint8(rand.Intn(255))
-			filterImg.AddLog(fmt.Sprintf("factor %d", filterImg.Factor))
-			ss := time.Now()
-			img := filterImg.RandomRed(id)
-			_, err = imagefilter.EncodeIMG(img, OUTPUT_DIR+alias+"/"+alias+"_"+fileProcessFlag+filepath.Ext(imgFile))
-			if err != nil {
-				filterImg.AddLog("Error encode img " + err.Error())
-				return
-			}
-			filterImg.AddLog("total process " + time.Since(ss).String())
-			wg.Done()
-		}(i)
+func thresholdFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:  "level",
+			Usage: "Gray level of the fixed threshold, 0 to 255",
+			Value: threshold.DEFAULT_LEVEL,
+		},
+		&cli.IntFlag{
+			Name:  "window",
+			Usage: "Odd size in pixels of the square averaged by the mean and gaussian thresholds",
+			Value: threshold.DEFAULT_WINDOW,
+		},
+		&cli.IntFlag{
+			Name:  "offset",
+			Usage: "Value subtracted from the average of the mean and gaussian thresholds",
+			Value: threshold.DEFAULT_OFFSET,
+		},
 	}
+}
 
-	go func() {
-		wg.Wait()
-		close(c)
-	}()
-
-	for l := range c {
-		fmt.Printf("\t%s\n", l)
-	}
+// thresholdOptions return the threshold flags with method
+func thresholdOptions(c *cli.Context, method string) threshold.Options {
+	return threshold.Options{Method: method, Level: c.Int("level"), Window: c.Int("window"), Offset: c.Int("offset")}
 }
 
-func randomColorBlueImgProcessing(alias string, imgFile string) {
-	var wg sync.WaitGroup
-	fileProcessFlag := "random_color_blue"
-	log.Println("process", fileProcessFlag)
-	s := time.Now()
-	img, err := imagefilter.DecodeImg(imgFile)
-	if err != nil {
-		log.Fatal(fmt.Errorf("decode-file %w", err))
+func textStyleFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "color",
+			Usage: "Draw every character in the color of the pixels it replace",
+		},
+		&cli.BoolFlag{
+			Name:  "dark",
+			Usage: "Draw on a black background",
+		},
 	}
-	xp := pixelextract.ExtractPixelFromImg(img)
-	log.Println("total open ", time.Since(s))
-
-	c := make(chan string)
-
-	t := 2
+}
 
-	filterImg := imagefilter.NewFilterImg(alias, img, xp, 0, c)
+// textStyle return the --color and --dark flags, false on the commands
+// without them
+func textStyle(c *cli.Context) imagefilter.TextStyle {
+	return imagefilter.TextStyle{Color: c.Bool("color"), Dark: c.Bool("dark")}
+}
 
-	for i := 1; i < t; i++ {
-		wg.Add(1)
-		go func(id int) {
-			filterImg.Factor = uint8(rand.Intn(255))
-			filterImg.AddLog(fmt.Sprintf("factor %d", filterImg.Factor))
-			ss := time.Now()
-			img := filterImg.RandomBlue(id)
-			_, err = imagefilter.EncodeIMG(img, OUTPUT_DIR+alias+"/"+alias+"_"+fileProcessFlag+filepath.Ext(imgFile))
-			if err != nil {
-				filterImg.AddLog("Error encode img " + err.Error())
-				return
-			}
-			filterImg.AddLog("total process " + time.Since(ss).String())
-			wg.Done()
-		}(i)
+func animationFlags(frames int) []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "animate",
+			Usage: "Write an animated gif instead of a still image",
+		},
+		&cli.BoolFlag{
+			Name:  "sequence",
+			Usage: "With animate, write the frames as numbered png files",
+		},
+		&cli.IntFlag{
+			Name:  "frames",
+			Usage: "Animation frames of a loop",
+			Value: frames,
+		},
+		&cli.IntFlag{
+			Name:  "delay",
+			Usage: "Animation frame delay in 100ths of a second",
+			Value: 4,
+		},
+		&cli.IntFlag{
+			Name:  "loop",
+			Usage: "Animation loop count, 0 loop forever, -1 play once",
+		},
 	}
+}
 
-	go func() {
-		wg.Wait()
-		close(c)
-	}()
+// inputFlags return the alias and file flags, the alias default to the
+// file name
+func inputFlags(c *cli.Context) (string, string, error) {
+	alias := c.String("alias")
+	inputFile := c.String("file")
+	if inputFile == "" {
+		return "", "", errors.New(`Required flag "file" not set`)
+	}
+	if alias == "" {
+		alias = naming.BaseName(inputFile)
+		if inputFile == "-" {
+			alias = "stdin"
+		}
+	}
+	return alias, inputFile, nil
+}
 
-	for l := range c {
-		fmt.Printf("\t%s\n", l)
+// inputExt return the extension of the input file, png when it has none
+// like stdin or its format can only be decoded, like webp
+func inputExt(inputFile string) string {
+	if ext := filepath.Ext(inputFile); ext != "" && imagefilter.EncodableFormat(ext) {
+		return ext
 	}
+	return ".png"
 }
 
-func randomColorGreenImgProcessing(alias string, imgFile string) {
-	var wg sync.WaitGroup
-	fileProcessFlag := "random_color_green"
-	log.Println("process", fileProcessFlag)
-	s := time.Now()
-	img, err := imagefilter.DecodeImg(imgFile)
-	if err != nil {
-		log.Fatal(fmt.Errorf("decode-file %w", err))
+func decodeInput(inputFile string) (image.Image, error) {
+	if inputFile == "-" {
+		img, _, err := imagefilter.Decode(os.Stdin)
+		return img, err
 	}
-	xp := pixelextract.ExtractPixelFromImg(img)
-	log.Println("total open ", time.Since(s))
-
-	c := make(chan string)
-
-	t := 2
+	return imagefilter.DecodeImg(inputFile)
+}
 
-	filterImg := imagefilter.NewFilterImg(alias, img, xp, 0, c)
+// encodeOutput write img to outFile, the format is the extension of outFile
+// or ext when it is written to stdout. An animation written to a format
+// without frames become a numbered sequence of files
+func encodeOutput(ctx context.Context, img image.Image, outFile string, ext string, o imagefilter.Options) error {
+	if outFile == "-" {
+		o.Format = ext
+		return imagefilter.Encode(os.Stdout, img, o)
+	}
+	if a, ok := img.(*imagefilter.Animation); ok && !imagefilter.AnimatedFormat(imagefilter.FormatFromPath(outFile)) {
+		paths, err := imagefilter.EncodeSequence(ctx, a, outFile, o)
+		if err == nil && len(paths) > 0 {
+			log.Printf("sequence of %d frames, %s ... %s", len(paths), paths[0], paths[len(paths)-1])
+		}
+		return err
+	}
+	return imagefilter.EncodeFile(ctx, img, outFile, o)
+}
 
-	for i := 1; i < t; i++ {
-		wg.Add(1)
-		go func(id int) {
-			filterImg.Factor = uint8(rand.Intn(255))
-			filterImg.AddLog(fmt.Sprintf("factor %d", filterImg.Factor))
-			ss := time.Now()
-			img := filterImg.RandomGreen(id)
-			_, err = imagefilter.EncodeIMG(img, OUTPUT_DIR+alias+"/"+alias+"_"+fileProcessFlag+filepath.Ext(imgFile))
-			if err != nil {
-				filterImg.AddLog("Error encode img " + err.Error())
-				return
+func commandFilters(c *cli.Context, alias string, fileProcessFlag string) ([]imagefilter.Filter, error) {
+	switch fileProcessFlag {
+	case "byte":
+		bs := imagefilter.ByteScale{Alias: alias, Scale: 85, Dither: c.String("dither"), TextStyle: textStyle(c)}
+		if bs.Dither != "" {
+			if err := dither.Check(bs.Dither, 2); err != nil {
+				return nil, err
 			}
-			filterImg.AddLog("total process " + time.Since(ss).String())
-			wg.Done()
-		}(i)
-	}
+		}
+		if method := c.String("threshold"); method != "" {
+			if bs.Dither != "" {
+				return nil, errors.New("byte: --dither and --threshold can not be used together")
+			}
+			bs.Threshold = thresholdOptions(c, method)
+			if err := bs.Threshold.Validate(); err != nil {
+				return nil, err
+			}
+		}
+		return []imagefilter.Filter{bs}, nil
+	case "threshold":
+		t := imagefilter.Threshold{Options: thresholdOptions(c, c.String("method"))}
+		if err := t.Validate(); err != nil {
+			return nil, err
+		}
+		return []imagefilter.Filter{t}, nil
+	case "dither":
+		d := imagefilter.Dither{Method: c.String("method"), Levels: c.Int("levels")}
+		if err := dither.Check(d.Method, d.Levels); err != nil {
+			return nil, err
+		}
+		return []imagefilter.Filter{d}, nil
+	case "character":
+		sw := time.Now()
+		weights := c.String("weights")
+		charInfo, err := experiment.LoadWeights(weights)
+		if err != nil {
+			return nil, fmt.Errorf("open-weight-file %w", err)
+		}
+		log.Println("total open weight table", weights, time.Since(sw))
+		cs := experiment.CharacterScale{Alias: alias, Info: charInfo, Scale: 85, TextStyle: textStyle(c)}
+		if err := cs.Validate(); err != nil {
+			return nil, fmt.Errorf("weights %s: %w", weights, err)
+		}
+		return []imagefilter.Filter{
+			imagefilter.Transparency{Alpha: 2},
+			cs,
+		}, nil
+	case "grayscale":
+		return []imagefilter.Filter{imagefilter.GreyScale{}}, nil
+	case "random_color":
+		return []imagefilter.Filter{imagefilter.RandomColor{Factor: randomFactor(fileProcessFlag)}}, nil
+	case "random_color_red":
+		return []imagefilter.Filter{imagefilter.RandomRed{Factor: randomFactor(fileProcessFlag)}}, nil
+	case "random_color_green":
+		return []imagefilter.Filter{imagefilter.RandomGreen{Factor: randomFactor(fileProcessFlag)}}, nil
+	case "random_color_blue":
+		return []imagefilter.Filter{imagefilter.RandomBlue{Factor: randomFactor(fileProcessFlag)}}, nil
+	case "channel_shift":
+		cs := imagefilter.ChannelShift{
+			Channels: c.String("channels"),
+			Zero:     c.String("zero"),
+			Order:    c.String("order"),
+			Mask:     c.String("mask"),
+			Size:     c.Int("size"),
+			Clamp:    c.Bool("clamp"),
+			Seed:     imagefilter.NewRand(runSeed, fileProcessFlag+"_noise").Int63(),
+		}
+		if c.IsSet("factor") {
+			factor := c.Uint("factor")
+			if factor > 255 {
+				return nil, fmt.Errorf("channel-shift: factor %d out of range 0-255", factor)
+			}
+			cs.Factor = uint8(factor)
+		} else {
+			cs.Factor = randomFactor(fileProcessFlag)
+		}
+		if err := cs.Validate(); err != nil {
+			return nil, err
+		}
+		return []imagefilter.Filter{cs}, nil
+	case "infinite":
+		if c.Bool("animate") {
+			return []imagefilter.Filter{experiment.InfiniteAnimation{
+				Percentage: 5,
+				Frames:     c.Int("frames"),
+				Delay:      c.Int("delay"),
+				LoopCount:  c.Int("loop"),
+			}}, nil
+		}
+		return []imagefilter.Filter{experiment.Infinite{Percentage: 5}}, nil
+	case "infinite_spiral":
+		if c.Bool("animate") {
+			return []imagefilter.Filter{experiment.InfiniteSpiralAnimation{
+				Angle:     5,
+				Frames:    c.Int("frames"),
+				Delay:     c.Int("delay"),
+				LoopCount: c.Int("loop"),
+			}}, nil
+		}
+		return []imagefilter.Filter{experiment.InfiniteSpiral{Angle: 5}}, nil
+	}
+	return nil, fmt.Errorf("filter %s not available", fileProcessFlag)
+}
 
-	go func() {
-		wg.Wait()
-		close(c)
-	}()
+// randomFactor draw the factor of the filter name from the run seed
+func randomFactor(name string) uint8 {
+	f := imagefilter.RandomFactor(imagefilter.NewRand(runSeed, name))
+	log.Printf("seed %d factor %d", runSeed, f)
+	return f
+}
 
-	for l := range c {
-		fmt.Printf("\t%s\n", l)
-	}
+// filterContext carry the --workers flag and the renderer to the filters,
+// it is done on SIGINT or after --timeout
+func filterContext(c *cli.Context) context.Context {
+	ctx := progress.WithObserver(c.Context, events)
+	return imagefilter.WithWorkers(ctx, c.Int("workers"))
 }
 
-func infiniteImgProcessing(alias string, imgFile string) {
-	var wg sync.WaitGroup
-	fileProcessFlag := "infinite"
-	log.Println("process", fileProcessFlag)
+// processImg decode imgFile, apply the filters and encode the result to the
+// path given by out
+func processImg(ctx context.Context, imgFile string, fields naming.Fields, out output, defaultExt string, filters ...imagefilter.Filter) error {
+	if err := out.validate(defaultExt); err != nil {
+		return err
+	}
 	s := time.Now()
-	img, err := imagefilter.DecodeImg(imgFile)
+	img, err := decodeInput(imgFile)
 	if err != nil {
-		log.Fatal(fmt.Errorf("decode-file %w", err))
+		return err
 	}
 	log.Println("total open ", time.Since(s))
-
-	c := make(chan string)
-
-	t := 2
-
-	for i := 1; i < t; i++ {
-		wg.Add(1)
-		go func(id int) {
-			ss := time.Now()
-			newImg := experiment.ImgInfinite(img, 5)
-			c <- fmt.Sprintf("percentage %d", 5)
-			_, err = imagefilter.EncodeIMG(newImg, OUTPUT_DIR+alias+"/"+alias+"_"+fileProcessFlag+filepath.Ext(imgFile))
-			if err != nil {
-				c <- "Error encode img " + err.Error()
-				return
-			}
-			c <- "total process " + time.Since(ss).String()
-			wg.Done()
-		}(i)
-	}
-
-	go func() {
-		wg.Wait()
-		close(c)
-	}()
-
-	for l := range c {
-		fmt.Printf("\t%s\n", l)
-	}
+	return filterImg(ctx, img, fields, out, defaultExt, filters...)
 }
 
-func infiniteSpiralImgProcessing(alias string, imgFile string) {
-	var wg sync.WaitGroup
-	fileProcessFlag := "infinite_spiral"
-	log.Println("process", fileProcessFlag)
-	s := time.Now()
-	img, err := imagefilter.DecodeImg(imgFile)
+// filterImg apply the filters to the decoded img and encode the result
+func filterImg(ctx context.Context, img image.Image, fields naming.Fields, out output, defaultExt string, filters ...imagefilter.Filter) error {
+	log.Println("process", fields.Filter)
+	ss := time.Now()
+	newImg, err := imagefilter.NewPipeline(nil, filters...).Apply(ctx, img)
 	if err != nil {
-		log.Fatal(fmt.Errorf("decode-file %w", err))
-	}
-	log.Println("total open ", time.Since(s))
-
-	c := make(chan string)
-
-	t := 2
-
-	for i := 1; i < t; i++ {
-		wg.Add(1)
-		go func(id int) {
-			ss := time.Now()
-			newImg := experiment.ImgInfiniteSpiral(img, 5)
-			_, err = imagefilter.EncodeIMG(newImg, fmt.Sprintf("%s%s/%s_%s%s", OUTPUT_DIR, alias, alias, fileProcessFlag, ".png"))
-			if err != nil {
-				c <- "Error encode img " + err.Error()
-				return
-			}
-			c <- "total process " + time.Since(ss).String()
-			wg.Done()
-		}(i)
+		return err
 	}
-
-	go func() {
-		wg.Wait()
-		close(c)
-	}()
-
-	for l := range c {
-		fmt.Printf("\t%s\n", l)
+	out.enc.Metadata = out.metadata(filters)
+	err = encodeOutput(ctx, newImg, out.path("", fields, defaultExt, newImg), out.ext(defaultExt), out.enc)
+	if err != nil {
+		return err
 	}
+	progress.Notify(events, progress.Event{Kind: progress.LOG, Message: "total process " + time.Since(ss).String()})
+	return nil
 }
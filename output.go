@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"github.com/victorvbello/img-processing/imagefilter"
+	"github.com/victorvbello/img-processing/utils/naming"
+)
+
+const (
+	DEFAULT_NAME_TEMPLATE       = "{alias}/{alias}_{filter}.{ext}"
+	DEFAULT_BATCH_NAME_TEMPLATE = "{name}.{ext}"
+	// DEFAULT_BATCH_CONVERTED_NAME_TEMPLATE name the batch files whose
+	// extension change, so in.png and in.jpg do not write the same file
+	DEFAULT_BATCH_CONVERTED_NAME_TEMPLATE = "{name}_{srcext}.{ext}"
+)
+
+// output route and name the processed files using the global flags
+type output struct {
+	file     string
+	dir      string
+	template string
+	format   string
+	seed     int64
+	enc      imagefilter.Options
+}
+
+func outputFromFlags(c *cli.Context, defaultTemplate string) output {
+	o := output{
+		file:     c.String("output"),
+		dir:      c.String("out-dir"),
+		template: c.String("name-template"),
+		format:   c.String("format"),
+		seed:     runSeed,
+		enc: imagefilter.Options{
+			JPEGQuality:     c.Int("jpeg-quality"),
+			PNGCompression:  c.String("png-compression"),
+			GIFPalette:      c.String("gif-palette"),
+			GIFDither:       c.Bool("gif-dither"),
+			TIFFCompression: c.String("tiff-compression"),
+		},
+	}
+	if !c.IsSet("name-template") {
+		o.template = defaultTemplate
+	}
+	return o
+}
+
+// metadata record the seed and the factor of the random filters so the
+// image can be made again, nil when no filter is random
+func (o output) metadata(filters []imagefilter.Filter) map[string]string {
+	var factors []string
+	for _, f := range filters {
+		if s, ok := f.(fmt.Stringer); ok {
+			factors = append(factors, s.String())
+		}
+	}
+	if len(factors) == 0 {
+		return nil
+	}
+	return map[string]string{
+		"Seed":    strconv.FormatInt(o.seed, 10),
+		"Filters": strings.Join(factors, ", "),
+	}
+}
+
+// ext return the --format extension or defaultExt when it is not set
+func (o output) ext(defaultExt string) string {
+	if o.format != "" {
+		return "." + strings.TrimPrefix(o.format, ".")
+	}
+	return defaultExt
+}
+
+// validate check the output format, the one of --output or else --format or
+// defaultExt, and the encoder flags before any image is decoded
+func (o output) validate(defaultExt string) error {
+	enc := o.enc
+	enc.Format = o.ext(defaultExt)
+	if o.file != "" && o.file != "-" {
+		enc.Format = imagefilter.FormatFromPath(o.file)
+	}
+	return enc.Validate()
+}
+
+// path return the output file of img inside dir, an empty dir use --out-dir.
+// --output replace the template when it is set
+func (o output) path(dir string, f naming.Fields, defaultExt string, img image.Image) string {
+	if o.file != "" {
+		return o.file
+	}
+	if dir == "" {
+		dir = o.dir
+	}
+	f.Ext = o.ext(defaultExt)
+	f.Width = img.Bounds().Dx()
+	f.Height = img.Bounds().Dy()
+	return filepath.Join(dir, naming.Expand(o.template, f))
+}
+
+// withExt replace the extension of path when --format is set
+func (o output) withExt(path string) string {
+	if o.format == "" {
+		return path
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + o.ext("")
+}
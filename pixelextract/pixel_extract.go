@@ -3,8 +3,6 @@ package pixelextract
 import (
 	"image"
 	"image/color"
-
-	colors "gopkg.in/go-playground/colors.v1"
 )
 
 type PixelColor struct {
@@ -16,40 +14,38 @@ type PixelColor struct {
 	ColorRGBA     color.RGBA
 }
 
-func (p PixelColor) ColorWeight() uint32 {
-	return uint32(p.ColorRGBA.R + p.ColorRGBA.G + p.ColorRGBA.B + p.ColorRGBA.A)
+// IsLight report whether c is perceived as light, same HSP threshold as
+// gopkg.in/go-playground/colors.v1
+func IsLight(c color.RGBA) bool {
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+	return 0.299*r*r+0.587*g*g+0.114*b*b > 130*130
 }
 
-func (p PixelColor) ColorGrayScale() uint32 {
+func ColorWeight(c color.RGBA) uint32 {
+	return uint32(c.R + c.G + c.B + c.A)
+}
+
+func ColorGrayScale(c color.RGBA) uint32 {
 	var fr uint32 = 19595
 	var fg uint32 = 38470
 	var fb uint32 = 7471
-	var cR uint32 = uint32(p.ColorRGBA.R)
-	var cG uint32 = uint32(p.ColorRGBA.G)
-	var cB uint32 = uint32(p.ColorRGBA.B)
+	var cR uint32 = uint32(c.R)
+	var cG uint32 = uint32(c.G)
+	var cB uint32 = uint32(c.B)
 	y := (fr*cR + fg*cG + fb*cB + 1<<15) >> 16 //greyScale 16bit
 	return y
 }
 
+func (p PixelColor) ColorWeight() uint32 {
+	return ColorWeight(p.ColorRGBA)
+}
+
+func (p PixelColor) ColorGrayScale() uint32 {
+	return ColorGrayScale(p.ColorRGBA)
+}
+
+// ExtractPixelFromImg make the PixelColor of every pixel, prefer Extract
+// for big images
 func ExtractPixelFromImg(img image.Image) []PixelColor {
-	var xp []PixelColor
-	bounds := img.Bounds()
-	width, height := bounds.Max.X, bounds.Max.Y
-
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			pColor := img.At(x, y)
-			RGBAColor := colors.FromStdColor(pColor)
-			originalRGBA := color.RGBAModel.Convert(pColor).(color.RGBA)
-			xp = append(xp, PixelColor{
-				X:             x,
-				Y:             y,
-				IsDark:        RGBAColor.IsDark(),
-				IsLight:       RGBAColor.IsLight(),
-				OriginalColor: pColor,
-				ColorRGBA:     originalRGBA,
-			})
-		}
-	}
-	return xp
+	return Extract(img).PixelColors()
 }
@@ -0,0 +1,143 @@
+package pixelextract
+
+import (
+	"image"
+	"image/color"
+)
+
+// Pixels hold the premultiplied RGBA of every pixel of an image in a single
+// row major slice, 4 bytes per pixel. The PixelColor of a pixel is made on
+// demand. Pix share the memory of an *image.RGBA whose rows are contiguous
+type Pixels struct {
+	img  image.Image
+	Rect image.Rectangle
+	Pix  []uint8
+}
+
+// Extract read img once, *image.RGBA, *image.NRGBA, *image.YCbCr and
+// *image.Gray read their Pix slices directly, any other image use At
+func Extract(img image.Image) *Pixels {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if src, ok := img.(*image.RGBA); ok && src.Stride == 4*width {
+		offset := src.PixOffset(bounds.Min.X, bounds.Min.Y)
+		return &Pixels{img, bounds, src.Pix[offset : offset+4*width*height]}
+	}
+
+	pix := make([]uint8, 4*width*height)
+	i := 0
+	switch src := img.(type) {
+	case *image.RGBA:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			offset := src.PixOffset(bounds.Min.X, y)
+			i += copy(pix[i:i+4*width], src.Pix[offset:offset+4*width])
+		}
+	case *image.NRGBA:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			row := src.Pix[src.PixOffset(bounds.Min.X, y):]
+			for x := 0; x < 4*width; x += 4 {
+				a := uint32(row[x+3])
+				a |= a << 8
+				for c := 0; c < 3; c++ {
+					v := uint32(row[x+c])
+					v |= v << 8
+					pix[i+c] = uint8((v * a / 0xffff) >> 8)
+				}
+				pix[i+3] = row[x+3]
+				i += 4
+			}
+		}
+	case *image.YCbCr:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				yi := src.YOffset(x, y)
+				ci := src.COffset(x, y)
+				pix[i], pix[i+1], pix[i+2] = color.YCbCrToRGB(src.Y[yi], src.Cb[ci], src.Cr[ci])
+				pix[i+3] = 255
+				i += 4
+			}
+		}
+	case *image.Gray:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			row := src.Pix[src.PixOffset(bounds.Min.X, y):]
+			for x := 0; x < width; x++ {
+				pix[i], pix[i+1], pix[i+2], pix[i+3] = row[x], row[x], row[x], 255
+				i += 4
+			}
+		}
+	default:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+				pix[i], pix[i+1], pix[i+2], pix[i+3] = c.R, c.G, c.B, c.A
+				i += 4
+			}
+		}
+	}
+	return &Pixels{img, bounds, pix}
+}
+
+func (p *Pixels) Len() int {
+	return len(p.Pix) / 4
+}
+
+// XY return the image coordinates of the pixel i
+func (p *Pixels) XY(i int) (int, int) {
+	width := p.Rect.Dx()
+	return p.Rect.Min.X + i%width, p.Rect.Min.Y + i/width
+}
+
+// Index return the position of the pixel x, y
+func (p *Pixels) Index(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Rect.Dx() + x - p.Rect.Min.X
+}
+
+func (p *Pixels) RGBA(i int) color.RGBA {
+	s := p.Pix[4*i : 4*i+4 : 4*i+4]
+	return color.RGBA{s[0], s[1], s[2], s[3]}
+}
+
+// Range call fn for every pixel in row major order until fn return false
+func (p *Pixels) Range(fn func(x, y int, c color.RGBA) bool) {
+	p.RangeRect(p.Rect, fn)
+}
+
+// RangeRect call fn for every pixel of r in row major order until fn return
+// false, r is clipped to the pixels bounds
+func (p *Pixels) RangeRect(r image.Rectangle, fn func(x, y int, c color.RGBA) bool) {
+	r = r.Intersect(p.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := 4 * p.Index(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x++ {
+			s := p.Pix[i : i+4 : i+4]
+			if !fn(x, y, color.RGBA{s[0], s[1], s[2], s[3]}) {
+				return
+			}
+			i += 4
+		}
+	}
+}
+
+// Pixel make the PixelColor of the pixel i
+func (p *Pixels) Pixel(i int) PixelColor {
+	x, y := p.XY(i)
+	c := p.RGBA(i)
+	light := IsLight(c)
+	return PixelColor{
+		X:             x,
+		Y:             y,
+		IsDark:        !light,
+		IsLight:       light,
+		OriginalColor: p.img.At(x, y),
+		ColorRGBA:     c,
+	}
+}
+
+// PixelColors make the PixelColor of every pixel
+func (p *Pixels) PixelColors() []PixelColor {
+	xp := make([]PixelColor, p.Len())
+	for i := range xp {
+		xp[i] = p.Pixel(i)
+	}
+	return xp
+}
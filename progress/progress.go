@@ -0,0 +1,77 @@
+package progress
+
+import (
+	"context"
+	"time"
+)
+
+// Kind of an Event
+type Kind string
+
+const (
+	STARTED  Kind = "started"
+	PROGRESS Kind = "progress"
+	DONE     Kind = "done"
+	WARNING  Kind = "warning"
+	FAILED   Kind = "failed"
+	LOG      Kind = "log"
+)
+
+// Event report a stage of the processing, Percent is the completion of the
+// whole job from 0 to 100 and Duration is set on DONE
+type Event struct {
+	Kind     Kind          `json:"kind"`
+	Stage    string        `json:"stage,omitempty"`
+	Step     int           `json:"step"`
+	Steps    int           `json:"steps,omitempty"`
+	Percent  float64       `json:"percent"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Message  string        `json:"message,omitempty"`
+	Time     time.Time     `json:"time"`
+}
+
+// Observer receive the events, Event is called from the goroutine doing the
+// work so it must be safe for concurrent use and return quickly
+type Observer interface {
+	Event(e Event)
+}
+
+// ObserverFunc adapts a plain function to the Observer interface
+type ObserverFunc func(e Event)
+
+func (f ObserverFunc) Event(e Event) {
+	f(e)
+}
+
+// Notify send e to o setting its Time, a nil o is ignored
+func Notify(o Observer, e Event) {
+	if o == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	o.Event(e)
+}
+
+// Percent return done of total as a percentage
+func Percent(done, total int) float64 {
+	if total < 1 {
+		return 100
+	}
+	return 100 * float64(done) / float64(total)
+}
+
+type observerKey struct{}
+
+// WithObserver set the observer of the filters applied with ctx, a nil o
+// silence them
+func WithObserver(ctx context.Context, o Observer) context.Context {
+	return context.WithValue(ctx, observerKey{}, o)
+}
+
+// FromContext return the observer set by WithObserver or nil
+func FromContext(ctx context.Context) Observer {
+	o, _ := ctx.Value(observerKey{}).(Observer)
+	return o
+}
@@ -0,0 +1,54 @@
+package progress
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ROWS_STEP is the percent of the rows between two events of Rows
+const ROWS_STEP = 5
+
+// Rows send a PROGRESS event every ROWS_STEP percent of the rows of an image
+// done, Done is safe for concurrent use and a nil *Rows do nothing
+type Rows struct {
+	o     Observer
+	total int64
+	done  int64
+	// sent is the last step notified, the events never go back even when
+	// the bands finish out of order
+	mu   sync.Mutex
+	sent int64
+}
+
+// NewRows report the progress of total rows to o, it return nil when o is
+// nil so the filters skip the counting
+func NewRows(o Observer, total int) *Rows {
+	if o == nil || total < 1 {
+		return nil
+	}
+	return &Rows{o: o, total: int64(total)}
+}
+
+// Done add n finished rows
+func (r *Rows) Done(n int) {
+	if r == nil {
+		return
+	}
+	done := atomic.AddInt64(&r.done, int64(n))
+	step := done * 100 / ROWS_STEP / r.total
+	if step <= atomic.LoadInt64(&r.sent) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if step <= r.sent {
+		return
+	}
+	atomic.StoreInt64(&r.sent, step)
+	Notify(r.o, Event{
+		Kind:    PROGRESS,
+		Percent: float64(step * ROWS_STEP),
+		Message: fmt.Sprintf("row %d/%d", done, r.total),
+	})
+}
@@ -0,0 +1,58 @@
+package progress
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRows(t *testing.T) {
+	var mu sync.Mutex
+	var percents []float64
+	o := ObserverFunc(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if e.Kind != PROGRESS {
+			t.Errorf("kind %s, want %s", e.Kind, PROGRESS)
+		}
+		percents = append(percents, e.Percent)
+	})
+	rows := NewRows(o, 200)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				rows.Done(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(percents) != 100/ROWS_STEP {
+		t.Fatalf("%d events, want %d", len(percents), 100/ROWS_STEP)
+	}
+	for i, p := range percents {
+		if want := float64((i + 1) * ROWS_STEP); p != want {
+			t.Errorf("event %d percent %v, want %v", i, p, want)
+		}
+	}
+}
+
+func TestRowsFewRows(t *testing.T) {
+	var percents []float64
+	rows := NewRows(ObserverFunc(func(e Event) { percents = append(percents, e.Percent) }), 3)
+	for i := 0; i < 3; i++ {
+		rows.Done(1)
+	}
+	if len(percents) != 3 || percents[2] != 100 {
+		t.Errorf("got %v, want an event per row ending at 100", percents)
+	}
+}
+
+func TestRowsNil(t *testing.T) {
+	if NewRows(nil, 10) != nil || NewRows(ObserverFunc(func(Event) {}), 0) != nil {
+		t.Error("expected a nil Rows without observer or rows")
+	}
+	var rows *Rows
+	rows.Done(1)
+}
@@ -0,0 +1,82 @@
+package recipe
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Params hold the parameters of a recipe step as decoded from JSON or YAML
+type Params map[string]interface{}
+
+func (p Params) Int(name string, def int) (int, error) {
+	v, ok := p[name]
+	if !ok {
+		return def, nil
+	}
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	case float64:
+		return int(n), nil
+	case string:
+		return strconv.Atoi(n)
+	}
+	return 0, fmt.Errorf("param %s: invalid int %v", name, v)
+}
+
+func (p Params) Float(name string, def float64) (float64, error) {
+	v, ok := p[name]
+	if !ok {
+		return def, nil
+	}
+	switch n := v.(type) {
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case string:
+		return strconv.ParseFloat(n, 64)
+	}
+	return 0, fmt.Errorf("param %s: invalid float %v", name, v)
+}
+
+func (p Params) Uint8(name string, def uint8) (uint8, error) {
+	n, err := p.Int(name, int(def))
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > 255 {
+		return 0, fmt.Errorf("param %s: %d out of range 0-255", name, n)
+	}
+	return uint8(n), nil
+}
+
+func (p Params) String(name string, def string) (string, error) {
+	v, ok := p[name]
+	if !ok {
+		return def, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("param %s: invalid string %v", name, v)
+	}
+	return s, nil
+}
+
+func (p Params) Bool(name string, def bool) (bool, error) {
+	v, ok := p[name]
+	if !ok {
+		return def, nil
+	}
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case string:
+		return strconv.ParseBool(b)
+	}
+	return false, fmt.Errorf("param %s: invalid bool %v", name, v)
+}
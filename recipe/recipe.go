@@ -0,0 +1,132 @@
+package recipe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/victorvbello/img-processing/imagefilter"
+)
+
+// Recipe describe a processing job: the input file, the steps to apply in
+// order and where to write the result
+type Recipe struct {
+	Alias  string `json:"alias" yaml:"alias"`
+	Input  string `json:"input" yaml:"input"`
+	Steps  []Step `json:"steps" yaml:"steps"`
+	Output Output `json:"output" yaml:"output"`
+	// Seed of the random steps, the same seed make the same image
+	Seed int64 `json:"seed,omitempty" yaml:"seed,omitempty"`
+}
+
+type Step struct {
+	Filter string `json:"filter" yaml:"filter"`
+	Params Params `json:"params" yaml:"params"`
+}
+
+type Output struct {
+	Path   string `json:"path" yaml:"path"`
+	Format string `json:"format" yaml:"format"`
+}
+
+// Load read a recipe file, files ending in .yaml or .yml are decoded as YAML
+// and everything else as JSON
+func Load(recipeFilepath string) (*Recipe, error) {
+	body, err := ioutil.ReadFile(recipeFilepath)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Parse(body, filepath.Ext(recipeFilepath))
+	if err != nil {
+		return nil, fmt.Errorf("recipe %s: %w", recipeFilepath, err)
+	}
+	return r, nil
+}
+
+// Parse decode a recipe, ext .yaml or .yml decode YAML and anything else JSON
+func Parse(body []byte, ext string) (*Recipe, error) {
+	var r Recipe
+	var err error
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(body, &r)
+	default:
+		err = json.Unmarshal(body, &r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (r *Recipe) Validate() error {
+	if r.Input == "" {
+		return errors.New("recipe: input is required")
+	}
+	if len(r.Steps) == 0 {
+		return errors.New("recipe: at least one step is required")
+	}
+	return nil
+}
+
+// GetAlias return the alias of the recipe, by default the output file name
+// without extension or the input one when there is no output path
+func (r *Recipe) GetAlias() string {
+	if r.Alias != "" {
+		return r.Alias
+	}
+	path := r.Output.Path
+	if path == "" {
+		path = r.Input
+	}
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// OutputExt return the extension of Output.Format, Output.Path or Input in
+// that order, png when the input has no extension or its format can not be
+// written
+func (r *Recipe) OutputExt() string {
+	if r.Output.Format != "" {
+		return "." + strings.TrimPrefix(r.Output.Format, ".")
+	}
+	if r.Output.Path != "" {
+		return filepath.Ext(r.Output.Path)
+	}
+	if ext := filepath.Ext(r.Input); ext != "" && imagefilter.EncodableFormat(ext) {
+		return ext
+	}
+	return ".png"
+}
+
+// OutputPath return the output path with the extension of Output.Format
+func (r *Recipe) OutputPath() string {
+	if r.Output.Format == "" {
+		return r.Output.Path
+	}
+	return strings.TrimSuffix(r.Output.Path, filepath.Ext(r.Output.Path)) + r.OutputExt()
+}
+
+// Filters build the filters of every step in order
+func (r *Recipe) Filters() ([]imagefilter.Filter, error) {
+	return BuildFilters(r.GetAlias(), r.Steps, r.Seed)
+}
+
+// BuildFilters build the filters of steps in order, alias name the job and
+// every step get its own random source from seed and its index
+func BuildFilters(alias string, steps []Step, seed int64) ([]imagefilter.Filter, error) {
+	filters := make([]imagefilter.Filter, 0, len(steps))
+	for i, s := range steps {
+		f, err := buildStep(alias, s, imagefilter.NewRand(seed, fmt.Sprintf("%d %s", i, s.Filter)))
+		if err != nil {
+			return nil, fmt.Errorf("recipe step %d: %w", i, err)
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
@@ -0,0 +1,143 @@
+package recipe
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/victorvbello/img-processing/imagefilter"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		ext   string
+		input string
+		steps []string
+		seed  int64
+		err   bool
+	}{
+		{
+			name:  "json",
+			body:  `{"input": "in.png", "seed": 7, "steps": [{"filter": "grayscale"}, {"filter": "resize", "params": {"scale": 50}}]}`,
+			ext:   ".json",
+			input: "in.png",
+			steps: []string{"grayscale", "resize"},
+			seed:  7,
+		},
+		{
+			name:  "yaml",
+			body:  "input: in.jpg\nsteps:\n  - filter: byte\n    params:\n      scale: 40\n",
+			ext:   ".YML",
+			input: "in.jpg",
+			steps: []string{"byte"},
+		},
+		{
+			name:  "unknown extension is json",
+			body:  `{"input": "in.gif", "steps": [{"filter": "rotate"}]}`,
+			ext:   ".txt",
+			input: "in.gif",
+			steps: []string{"rotate"},
+		},
+		{name: "bad json", body: `{"input": `, ext: ".json", err: true},
+		{name: "yaml as json", body: "input: in.png\n", ext: ".json", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse([]byte(tt.body), tt.ext)
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Input != tt.input || r.Seed != tt.seed || len(r.Steps) != len(tt.steps) {
+				t.Fatalf("got input %q seed %d steps %v", r.Input, r.Seed, r.Steps)
+			}
+			for i, s := range r.Steps {
+				if s.Filter != tt.steps[i] {
+					t.Errorf("step %d: got %q, want %q", i, s.Filter, tt.steps[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBuildFilters(t *testing.T) {
+	tests := []struct {
+		name string
+		step Step
+		err  string
+	}{
+		{name: "grayscale", step: Step{Filter: "grayscale"}},
+		{name: "infinite percentage not dividing 100", step: Step{Filter: "infinite", Params: Params{"percentage": 7.0}}},
+		{name: "infinite percentage 0", step: Step{Filter: "infinite", Params: Params{"percentage": 0.0}}, err: "out of range"},
+		{name: "infinite percentage 100", step: Step{Filter: "infinite", Params: Params{"percentage": 100.0}}, err: "out of range"},
+		{name: "infinite spiral angle 0", step: Step{Filter: "infinite-spiral", Params: Params{"angle": 0.0}}, err: "out of range"},
+		{name: "infinite spiral negative angle", step: Step{Filter: "infinite-spiral", Params: Params{"angle": -3.0}}, err: "out of range"},
+		{name: "factor above 255", step: Step{Filter: "random-color", Params: Params{"factor": 300.0}}, err: "out of range"},
+		{name: "bad int", step: Step{Filter: "resize", Params: Params{"scale": "big"}}, err: "invalid syntax"},
+		{name: "resize scale 99", step: Step{Filter: "resize", Params: Params{"scale": 99.0}}},
+		{name: "resize scale 100", step: Step{Filter: "resize", Params: Params{"scale": 100.0}}, err: "out of range"},
+		{name: "resize scale 150", step: Step{Filter: "resize", Params: Params{"scale": 150.0}}, err: "out of range"},
+		{name: "resize negative scale", step: Step{Filter: "resize", Params: Params{"scale": -50.0}}, err: "out of range"},
+		{name: "byte scale 100", step: Step{Filter: "byte", Params: Params{"scale": 100.0}}, err: "out of range"},
+		{name: "ascii-art negative scale", step: Step{Filter: "ascii-art", Params: Params{"scale": -1.0}}, err: "out of range"},
+		{name: "unknown step", step: Step{Filter: "blur"}, err: "not available"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters, err := BuildFilters("alias", []Step{tt.step}, 1)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(filters) != 1 {
+				t.Fatalf("got %d filters", len(filters))
+			}
+		})
+	}
+}
+
+func TestBuildFiltersSeed(t *testing.T) {
+	steps := []Step{{Filter: "random-color"}, {Filter: "random-color"}}
+	a, err := BuildFilters("alias", steps, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := BuildFilters("alias", steps, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range a {
+		if a[i].(imagefilter.RandomColor) != b[i].(imagefilter.RandomColor) {
+			t.Errorf("step %d: the same seed gave %v and %v", i, a[i], b[i])
+		}
+	}
+}
+
+func TestOutputExt(t *testing.T) {
+	tests := []struct {
+		r    Recipe
+		want string
+	}{
+		{Recipe{Input: "in.jpg"}, ".jpg"},
+		{Recipe{Input: "in.webp"}, ".png"},
+		{Recipe{Input: "in"}, ".png"},
+		{Recipe{Input: "in.webp", Output: Output{Path: "out.gif"}}, ".gif"},
+		{Recipe{Input: "in.webp", Output: Output{Path: "out.gif", Format: "bmp"}}, ".bmp"},
+	}
+	for _, tt := range tests {
+		if got := tt.r.OutputExt(); got != tt.want {
+			t.Errorf("OutputExt(%+v) = %q, want %q", tt.r, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package recipe
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/victorvbello/img-processing/dither"
+	"github.com/victorvbello/img-processing/experiment"
+	"github.com/victorvbello/img-processing/imagefilter"
+	"github.com/victorvbello/img-processing/threshold"
+)
+
+const DEFAULT_WEIGHTS = experiment.DEFAULT_WEIGHT_TABLE
+
+// StepBuilder make the filter of a step from its params, alias name the job
+// and rnd is the random source of the step, seeded from the recipe seed
+type StepBuilder func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error)
+
+var steps = map[string]StepBuilder{}
+
+// Register make a step available to recipes under name
+func Register(name string, b StepBuilder) {
+	steps[name] = b
+}
+
+// Steps return the registered step names sorted
+func Steps() []string {
+	names := make([]string, 0, len(steps))
+	for n := range steps {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func buildStep(alias string, s Step, rnd *rand.Rand) (imagefilter.Filter, error) {
+	b, ok := steps[s.Filter]
+	if !ok {
+		return nil, fmt.Errorf("step %s not available", s.Filter)
+	}
+	return b(alias, s.Params, rnd)
+}
+
+func randomFactor(p Params, rnd *rand.Rand) (uint8, error) {
+	return p.Uint8("factor", imagefilter.RandomFactor(rnd))
+}
+
+// textStyle read the color and dark params of the text steps
+func textStyle(p Params) (imagefilter.TextStyle, error) {
+	var style imagefilter.TextStyle
+	var err error
+	if style.Color, err = p.Bool("color", false); err != nil {
+		return style, err
+	}
+	style.Dark, err = p.Bool("dark", false)
+	return style, err
+}
+
+// thresholdOptions read the level, window and offset params of method
+func thresholdOptions(p Params, method string) (threshold.Options, error) {
+	o := threshold.DefaultOptions(method)
+	var err error
+	if o.Level, err = p.Int("level", o.Level); err != nil {
+		return o, err
+	}
+	if o.Window, err = p.Int("window", o.Window); err != nil {
+		return o, err
+	}
+	if o.Offset, err = p.Int("offset", o.Offset); err != nil {
+		return o, err
+	}
+	return o, o.Validate()
+}
+
+func init() {
+	Register("grayscale", func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error) {
+		return imagefilter.GreyScale{}, nil
+	})
+	Register("random-color", func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error) {
+		f, err := randomFactor(p, rnd)
+		return imagefilter.RandomColor{Factor: f}, err
+	})
+	Register("random-color-red", func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error) {
+		f, err := randomFactor(p, rnd)
+		return imagefilter.RandomRed{Factor: f}, err
+	})
+	Register("random-color-green", func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error) {
+		f, err := randomFactor(p, rnd)
+		return imagefilter.RandomGreen{Factor: f}, err
+	})
+	Register("random-color-blue", func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error) {
+		f, err := randomFactor(p, rnd)
+		return imagefilter.RandomBlue{Factor: f}, err
+	})
+	Register("channel-shift", func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error) {
+		var cs imagefilter.ChannelShift
+		var err error
+		if cs.Factor, err = randomFactor(p, rnd); err != nil {
+			return nil, err
+		}
+		if cs.Channels, err = p.String("channels", "rgb"); err != nil {
+			return nil, err
+		}
+		if cs.Zero, err = p.String("zero", ""); err != nil {
+			return nil, err
+		}
+		if cs.Order, err = p.String("order", imagefilter.CHANNELS); err != nil {
+			return nil, err
+		}
+		if cs.Mask, err = p.String("mask", imagefilter.MASK_ALL); err != nil {
+			return nil, err
+		}
+		if cs.Size, err = p.Int("size", 1); err != nil {
+			return nil, err
+		}
+		if cs.Clamp, err = p.Bool("clamp", false); err != nil {
+			return nil, err
+		}
+		cs.Seed = rnd.Int63()
+		return cs, cs.Validate()
+	})
+	Register("resize", func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error) {
+		scale, err := p.Int("scale", 0)
+		if err != nil {
+			return nil, err
+		}
+		return imagefilter.Resize{Scale: scale}, imagefilter.CheckScale(scale)
+	})
+	Register("transparency", func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error) {
+		alpha, err := p.Uint8("alpha", 2)
+		return imagefilter.Transparency{Alpha: alpha}, err
+	})
+	Register("rotate", func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error) {
+		angle, err := p.Float("angle", 0)
+		return imagefilter.Rotate{Angle: angle}, err
+	})
+	Register("byte", func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error) {
+		scale, err := p.Int("scale", 85)
+		if err != nil {
+			return nil, err
+		}
+		if err := imagefilter.CheckScale(scale); err != nil {
+			return nil, err
+		}
+		method, err := p.String("dither", "")
+		if err != nil {
+			return nil, err
+		}
+		if method != "" {
+			if err := dither.Check(method, 2); err != nil {
+				return nil, err
+			}
+		}
+		bs := imagefilter.ByteScale{Alias: alias, Scale: scale, Dither: method}
+		if method, err = p.String("threshold", ""); err != nil {
+			return nil, err
+		}
+		if method != "" {
+			if bs.Threshold, err = thresholdOptions(p, method); err != nil {
+				return nil, err
+			}
+		}
+		bs.TextStyle, err = textStyle(p)
+		return bs, err
+	})
+	Register("threshold", func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error) {
+		method, err := p.String("method", threshold.OTSU)
+		if err != nil {
+			return nil, err
+		}
+		o, err := thresholdOptions(p, method)
+		return imagefilter.Threshold{Options: o}, err
+	})
+	Register("dither", func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error) {
+		method, err := p.String("method", dither.FLOYD_STEINBERG)
+		if err != nil {
+			return nil, err
+		}
+		levels, err := p.Int("levels", 2)
+		if err != nil {
+			return nil, err
+		}
+		return imagefilter.Dither{Method: method, Levels: levels}, dither.Check(method, levels)
+	})
+	Register("ascii-art", func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error) {
+		var charInfo experiment.CharacterInfo
+		scale, err := p.Int("scale", 85)
+		if err != nil {
+			return nil, err
+		}
+		if err := imagefilter.CheckScale(scale); err != nil {
+			return nil, err
+		}
+		charset, err := p.String("charset", "")
+		if err != nil {
+			return nil, err
+		}
+		if charset != "" {
+			charInfo = experiment.CharacterInfoFromRamp(charset)
+		} else {
+			weights, err := p.String("weights", DEFAULT_WEIGHTS)
+			if err != nil {
+				return nil, err
+			}
+			charInfo, err = experiment.LoadWeights(weights)
+			if err != nil {
+				return nil, fmt.Errorf("open-weight-file %w", err)
+			}
+		}
+		style, err := textStyle(p)
+		if err != nil {
+			return nil, err
+		}
+		cs := experiment.CharacterScale{Alias: alias, Info: charInfo, Scale: scale, TextStyle: style}
+		return cs, cs.Validate()
+	})
+	Register("infinite", func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error) {
+		percentage, err := p.Int("percentage", 5)
+		if err != nil {
+			return nil, err
+		}
+		if err := experiment.CheckLayerStep("percentage", percentage); err != nil {
+			return nil, err
+		}
+		animate, err := p.Bool("animate", false)
+		if err != nil || !animate {
+			return experiment.Infinite{Percentage: percentage}, err
+		}
+		ia := experiment.InfiniteAnimation{Percentage: percentage}
+		if ia.Frames, err = p.Int("frames", 10); err != nil {
+			return nil, err
+		}
+		if ia.Delay, err = p.Int("delay", 4); err != nil {
+			return nil, err
+		}
+		ia.LoopCount, err = p.Int("loop", 0)
+		return ia, err
+	})
+	Register("infinite-spiral", func(alias string, p Params, rnd *rand.Rand) (imagefilter.Filter, error) {
+		angle, err := p.Int("angle", 5)
+		if err != nil {
+			return nil, err
+		}
+		if err := experiment.CheckLayerStep("angle", angle); err != nil {
+			return nil, err
+		}
+		animate, err := p.Bool("animate", false)
+		if err != nil || !animate {
+			return experiment.InfiniteSpiral{Angle: angle}, err
+		}
+		isa := experiment.InfiniteSpiralAnimation{Angle: angle}
+		if isa.Frames, err = p.Int("frames", 36); err != nil {
+			return nil, err
+		}
+		if isa.Delay, err = p.Int("delay", 4); err != nil {
+			return nil, err
+		}
+		isa.LoopCount, err = p.Int("loop", 0)
+		return isa, err
+	})
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/victorvbello/img-processing/progress"
+)
+
+const BAR_WIDTH = 30
+
+// renderer print the progress events of the filters, it is also the output
+// of the log package so the cli messages and the events are not mixed
+type renderer interface {
+	progress.Observer
+	io.Writer
+	Close() error
+}
+
+// newRenderer return the renderer of format (plain, json or bar), the events
+// are written to w except for the bar that is always drawn on stderr
+func newRenderer(format string, w io.Writer) (renderer, error) {
+	switch format {
+	case "", "plain":
+		return &plainRenderer{w: w, logs: os.Stderr}, nil
+	case "json":
+		log.SetFlags(0)
+		return &jsonRenderer{enc: json.NewEncoder(w)}, nil
+	case "bar":
+		return &barRenderer{w: os.Stderr}, nil
+	}
+	return nil, fmt.Errorf("log-format %q not available, use plain, json or bar", format)
+}
+
+// plainRenderer print one line per finished step, log and warning, the log
+// package messages keep going to stderr
+type plainRenderer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	logs io.Writer
+}
+
+func (r *plainRenderer) Event(e progress.Event) {
+	var line string
+	switch e.Kind {
+	case progress.DONE:
+		line = fmt.Sprintf("%s, total => %v", e.Stage, e.Duration)
+	case progress.FAILED:
+		line = fmt.Sprintf("fail %s: %s", e.Stage, e.Message)
+	case progress.WARNING:
+		line = fmt.Sprintf("warning %s: %s", e.Stage, e.Message)
+	case progress.LOG:
+		line = e.Message
+		if e.Stage != "" {
+			line = e.Stage + ": " + line
+		}
+	default:
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.w, "\t%s\n", line)
+}
+
+func (r *plainRenderer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.logs.Write(p)
+}
+
+func (r *plainRenderer) Close() error {
+	return nil
+}
+
+// jsonRenderer write every event as a JSON line, the log package messages
+// become LOG events
+type jsonRenderer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (r *jsonRenderer) Event(e progress.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enc.Encode(e)
+}
+
+func (r *jsonRenderer) Write(p []byte) (int, error) {
+	progress.Notify(r, progress.Event{Kind: progress.LOG, Message: strings.TrimSpace(string(p))})
+	return len(p), nil
+}
+
+func (r *jsonRenderer) Close() error {
+	return nil
+}
+
+// barRenderer redraw a progress bar of the current stage on a single line,
+// logs, warnings and failures are printed above it
+type barRenderer struct {
+	mu      sync.Mutex
+	w       io.Writer
+	stage   string
+	message string
+	percent float64
+	drawn   bool
+}
+
+func (r *barRenderer) Event(e progress.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch e.Kind {
+	case progress.LOG:
+		r.println(e.Message)
+		return
+	case progress.WARNING:
+		r.println(fmt.Sprintf("warning %s: %s", e.Stage, e.Message))
+		return
+	case progress.FAILED:
+		r.println(fmt.Sprintf("fail %s: %s", e.Stage, e.Message))
+	}
+	r.stage, r.message, r.percent = e.Stage, e.Message, e.Percent
+	r.draw()
+}
+
+func (r *barRenderer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.println(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// println clear the bar, print l and draw the bar again
+func (r *barRenderer) println(l string) {
+	fmt.Fprintf(r.w, "\r\033[K%s\n", l)
+	if r.drawn {
+		r.draw()
+	}
+}
+
+func (r *barRenderer) draw() {
+	done := int(r.percent * BAR_WIDTH / 100)
+	if done > BAR_WIDTH {
+		done = BAR_WIDTH
+	}
+	fmt.Fprintf(r.w, "\r\033[K[%s%s] %3.0f%% %s %s", strings.Repeat("#", done), strings.Repeat("-", BAR_WIDTH-done), r.percent, r.stage, r.message)
+	r.drawn = true
+}
+
+func (r *barRenderer) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.drawn {
+		fmt.Fprintln(r.w)
+	}
+	return nil
+}
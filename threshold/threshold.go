@@ -0,0 +1,252 @@
+package threshold
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/victorvbello/img-processing/pixelextract"
+	"github.com/victorvbello/img-processing/progress"
+)
+
+const (
+	FIXED    = "fixed"
+	OTSU     = "otsu"
+	MEAN     = "mean"
+	GAUSSIAN = "gaussian"
+
+	DEFAULT_LEVEL  = 128
+	DEFAULT_WINDOW = 15
+	DEFAULT_OFFSET = 5
+)
+
+// Palette is black and white, the images made by Binarize are written as a
+// 1-bit png
+var Palette = color.Palette{color.Black, color.White}
+
+// Methods return the names accepted by Options.Method
+func Methods() []string {
+	return []string{FIXED, OTSU, MEAN, GAUSSIAN}
+}
+
+// Options choose how Binarize pick the white pixels
+type Options struct {
+	// Method is FIXED (gray above Level), OTSU (level picked from the
+	// histogram), MEAN or GAUSSIAN (gray above the average of the Window
+	// around the pixel minus Offset)
+	Method string
+	Level  int
+	Window int
+	Offset int
+}
+
+// DefaultOptions return the options of method with the default level, window
+// and offset
+func DefaultOptions(method string) Options {
+	return Options{Method: method, Level: DEFAULT_LEVEL, Window: DEFAULT_WINDOW, Offset: DEFAULT_OFFSET}
+}
+
+func (o Options) Validate() error {
+	switch o.Method {
+	case FIXED, OTSU:
+	case MEAN, GAUSSIAN:
+		if o.Window < 3 || o.Window%2 == 0 {
+			return fmt.Errorf("threshold window %d must be odd and at least 3", o.Window)
+		}
+	default:
+		return fmt.Errorf("threshold method %q not available, use %s", o.Method, strings.Join(Methods(), ", "))
+	}
+	if o.Level < 0 || o.Level > 255 {
+		return fmt.Errorf("threshold level %d out of range 0 to 255", o.Level)
+	}
+	return nil
+}
+
+// Binarize make the pixels of img black or white using o
+func Binarize(ctx context.Context, img image.Image, o Options) (*image.Paletted, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	gray := make([]float64, w*h)
+	pixelextract.Extract(img).Range(func(x, y int, c color.RGBA) bool {
+		gray[(y-bounds.Min.Y)*w+x-bounds.Min.X] = float64(pixelextract.ColorGrayScale(c))
+		return true
+	})
+
+	// the rows are walked once by boxMean, twice by gaussianMean and once
+	// to pick the white pixels
+	passes := map[string]int{FIXED: 1, OTSU: 1, MEAN: 2, GAUSSIAN: 3}[o.Method]
+	rows := progress.NewRows(progress.FromContext(ctx), passes*h)
+	var limits []float64
+	var err error
+	switch o.Method {
+	case FIXED:
+		limits = uniform(len(gray), float64(o.Level))
+	case OTSU:
+		limits = uniform(len(gray), float64(Otsu(gray)))
+	case MEAN:
+		limits, err = boxMean(ctx, rows, gray, w, h, o.Window/2)
+	case GAUSSIAN:
+		limits, err = gaussianMean(ctx, rows, gray, w, h, o.Window)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if o.Method == MEAN || o.Method == GAUSSIAN {
+		for i := range limits {
+			limits[i] -= float64(o.Offset)
+		}
+	}
+
+	dst := image.NewPaletted(bounds, Palette)
+	for y := 0; y < h; y++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for x := 0; x < w; x++ {
+			if gray[y*w+x] > limits[y*w+x] {
+				dst.Pix[y*dst.Stride+x] = 1
+			}
+		}
+		rows.Done(1)
+	}
+	return dst, nil
+}
+
+func uniform(n int, v float64) []float64 {
+	limits := make([]float64, n)
+	for i := range limits {
+		limits[i] = v
+	}
+	return limits
+}
+
+// Otsu return the level that split the gray values, 0 to 255, in the two
+// classes with the highest variance between them
+func Otsu(gray []float64) int {
+	var histogram [256]int
+	for _, v := range gray {
+		histogram[int(v)]++
+	}
+	var sum float64
+	for i, n := range histogram {
+		sum += float64(i * n)
+	}
+	var sumBackground float64
+	var background int
+	best, bestVariance := 0, -1.0
+	for t, n := range histogram {
+		background += n
+		if background == 0 {
+			continue
+		}
+		foreground := len(gray) - background
+		if foreground == 0 {
+			break
+		}
+		sumBackground += float64(t * n)
+		meanBackground := sumBackground / float64(background)
+		meanForeground := (sum - sumBackground) / float64(foreground)
+		variance := float64(background) * float64(foreground) * (meanBackground - meanForeground) * (meanBackground - meanForeground)
+		if variance > bestVariance {
+			best, bestVariance = t, variance
+		}
+	}
+	return best
+}
+
+// boxMean return the average of the (2r+1) square around every pixel using
+// a summed area table, the window is cut at the borders. Every row is
+// reported to rows
+func boxMean(ctx context.Context, rows *progress.Rows, gray []float64, w, h, r int) ([]float64, error) {
+	sat := make([]float64, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		var row float64
+		for x := 0; x < w; x++ {
+			row += gray[y*w+x]
+			sat[(y+1)*(w+1)+x+1] = sat[y*(w+1)+x+1] + row
+		}
+	}
+	means := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		minY, maxY := max(y-r, 0), min(y+r+1, h)
+		for x := 0; x < w; x++ {
+			minX, maxX := max(x-r, 0), min(x+r+1, w)
+			sum := sat[maxY*(w+1)+maxX] - sat[minY*(w+1)+maxX] - sat[maxY*(w+1)+minX] + sat[minY*(w+1)+minX]
+			means[y*w+x] = sum / float64((maxY-minY)*(maxX-minX))
+		}
+		rows.Done(1)
+	}
+	return means, nil
+}
+
+// gaussianMean return the gaussian weighted average of the window around
+// every pixel, sigma is a sixth of the window, the kernel is normalized at
+// the borders. Every row of both passes is reported to rows
+func gaussianMean(ctx context.Context, rows *progress.Rows, gray []float64, w, h, window int) ([]float64, error) {
+	r := window / 2
+	sigma := float64(window) / 6
+	kernel := make([]float64, window)
+	for i := range kernel {
+		d := float64(i - r)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+	}
+	// the blur is separable, rows first then columns
+	blurred := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for x := 0; x < w; x++ {
+			var sum, weight float64
+			for i, k := range kernel {
+				if sx := x + i - r; sx >= 0 && sx < w {
+					sum += gray[y*w+sx] * k
+					weight += k
+				}
+			}
+			blurred[y*w+x] = sum / weight
+		}
+		rows.Done(1)
+	}
+	means := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for x := 0; x < w; x++ {
+			var sum, weight float64
+			for i, k := range kernel {
+				if sy := y + i - r; sy >= 0 && sy < h {
+					sum += blurred[sy*w+x] * k
+					weight += k
+				}
+			}
+			means[y*w+x] = sum / weight
+		}
+		rows.Done(1)
+	}
+	return means, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,184 @@
+package threshold
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		o   Options
+		err string
+	}{
+		{DefaultOptions(FIXED), ""},
+		{DefaultOptions(OTSU), ""},
+		{DefaultOptions(MEAN), ""},
+		{DefaultOptions(GAUSSIAN), ""},
+		{Options{Method: FIXED, Level: 0}, ""},
+		{Options{Method: FIXED, Level: 255}, ""},
+		{Options{Method: FIXED, Window: 4}, ""},
+		{Options{Method: MEAN, Window: 3}, ""},
+		{Options{Method: FIXED, Level: -1}, "out of range"},
+		{Options{Method: OTSU, Level: 256}, "out of range"},
+		{Options{Method: MEAN, Window: 1}, "must be odd"},
+		{Options{Method: GAUSSIAN, Window: 16}, "must be odd"},
+		{Options{Method: "median", Window: 3}, "not available"},
+		{Options{}, "not available"},
+	}
+	for _, tt := range tests {
+		err := tt.o.Validate()
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%+v: %v", tt.o, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%+v: got error %v, want %q", tt.o, err, tt.err)
+		}
+	}
+}
+
+func TestOtsu(t *testing.T) {
+	tests := []struct {
+		name string
+		gray []float64
+		want int
+	}{
+		{"empty", nil, 0},
+		{"single value", []float64{50, 50, 50}, 0},
+		{"black and white", []float64{0, 255}, 0},
+		{"two clusters", []float64{10, 12, 10, 200, 210, 200}, 12},
+		{"unbalanced", []float64{20, 20, 20, 20, 20, 20, 90, 100}, 20},
+		{"fractions truncated", []float64{30.9, 30.2, 180.5}, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Otsu(tt.gray); got != tt.want {
+				t.Errorf("Otsu = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoxMean(t *testing.T) {
+	// 0 1 2
+	// 3 4 5
+	// 6 7 8
+	gray := []float64{0, 1, 2, 3, 4, 5, 6, 7, 8}
+	tests := []struct {
+		name string
+		r    int
+		want []float64
+	}{
+		{"radius 0", 0, gray},
+		{"radius 1", 1, []float64{2, 2.5, 3, 3.5, 4, 4.5, 5, 5.5, 6}},
+		{"window larger than the image", 5, []float64{4, 4, 4, 4, 4, 4, 4, 4, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := boxMean(context.Background(), nil, gray, 3, 3, tt.r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range tt.want {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Errorf("pixel %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBoxMeanNotSquare(t *testing.T) {
+	// 1 2 3 4
+	gray := []float64{1, 2, 3, 4}
+	got, err := boxMean(context.Background(), nil, gray, 4, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{1.5, 2, 3, 3.5}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("pixel %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestGaussianMean(t *testing.T) {
+	flat := uniform(20, 77)
+	got, err := gaussianMean(context.Background(), nil, flat, 5, 4, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range got {
+		if math.Abs(v-77) > 1e-9 {
+			t.Errorf("flat pixel %d = %v, want 77", i, v)
+		}
+	}
+
+	// an impulse spread the same way on every side
+	impulse := make([]float64, 25)
+	impulse[12] = 100
+	got, err = gaussianMean(context.Background(), nil, impulse, 5, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pair := range [][2]int{{7, 17}, {11, 13}, {7, 11}, {6, 18}, {6, 8}} {
+		if math.Abs(got[pair[0]]-got[pair[1]]) > 1e-9 {
+			t.Errorf("pixels %d and %d = %v and %v, want the same", pair[0], pair[1], got[pair[0]], got[pair[1]])
+		}
+	}
+	if got[12] <= got[7] || got[7] <= got[6] || got[0] != 0 {
+		t.Errorf("impulse not centered: %v", got)
+	}
+}
+
+func TestMeansCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	gray := uniform(9, 1)
+	if _, err := boxMean(ctx, nil, gray, 3, 3, 1); err != context.Canceled {
+		t.Errorf("boxMean err = %v, want %v", err, context.Canceled)
+	}
+	if _, err := gaussianMean(ctx, nil, gray, 3, 3, 3); err != context.Canceled {
+		t.Errorf("gaussianMean err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestBinarize(t *testing.T) {
+	img := image.NewGray(image.Rect(2, 3, 6, 4))
+	copy(img.Pix, []uint8{0, 128, 129, 255})
+	tests := []struct {
+		o    Options
+		want []uint8
+	}{
+		{Options{Method: FIXED, Level: 128}, []uint8{0, 0, 1, 1}},
+		{Options{Method: FIXED, Level: 0}, []uint8{0, 1, 1, 1}},
+		{Options{Method: OTSU}, []uint8{0, 1, 1, 1}},
+	}
+	for _, tt := range tests {
+		dst, err := Binarize(context.Background(), img, tt.o)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.o, err)
+		}
+		if dst.Bounds() != img.Bounds() {
+			t.Errorf("%+v: bounds %v, want %v", tt.o, dst.Bounds(), img.Bounds())
+		}
+		for i, want := range tt.want {
+			if dst.Pix[i] != want {
+				t.Errorf("%+v: pixel %d = %d, want %d", tt.o, i, dst.Pix[i], want)
+			}
+		}
+	}
+	if _, err := Binarize(context.Background(), img, Options{Method: MEAN, Window: 2}); err == nil {
+		t.Error("expected an error for an even window")
+	}
+	if c := color.GrayModel.Convert(Palette[1]).(color.Gray); c.Y != 255 {
+		t.Errorf("Palette[1] = %v, want white", c)
+	}
+}
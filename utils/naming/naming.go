@@ -0,0 +1,39 @@
+package naming
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Fields are the values available to a name template:
+// {alias}, {filter}, {name}, {srcext}, {ext}, {w} and {h}
+type Fields struct {
+	Alias  string
+	Filter string
+	Name   string
+	// SrcExt is the extension of the input file
+	SrcExt string
+	Ext    string
+	Width  int
+	Height int
+}
+
+// Expand replace the placeholders of template with the values of f
+func Expand(template string, f Fields) string {
+	return strings.NewReplacer(
+		"{alias}", f.Alias,
+		"{filter}", f.Filter,
+		"{name}", f.Name,
+		"{srcext}", strings.TrimPrefix(f.SrcExt, "."),
+		"{ext}", strings.TrimPrefix(f.Ext, "."),
+		"{w}", strconv.Itoa(f.Width),
+		"{h}", strconv.Itoa(f.Height),
+	).Replace(template)
+}
+
+// BaseName return the file name of path without directory and extension
+func BaseName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
@@ -0,0 +1,21 @@
+package naming
+
+import "testing"
+
+func TestExpand(t *testing.T) {
+	f := Fields{Alias: "cat", Filter: "grayscale", Name: "in", SrcExt: ".jpg", Ext: ".png", Width: 40, Height: 30}
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{alias}/{alias}_{filter}.{ext}", "cat/cat_grayscale.png"},
+		{"{name}_{srcext}.{ext}", "in_jpg.png"},
+		{"{name}_{w}x{h}.{ext}", "in_40x30.png"},
+		{"plain", "plain"},
+	}
+	for _, tt := range tests {
+		if got := Expand(tt.template, f); got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}